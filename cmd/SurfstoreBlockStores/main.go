@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"cse224/proj4/pkg/surfstore"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Arguments
+const ARG_COUNT int = 1
+
+// Usage strings
+const USAGE_STRING = "./run-blockstores.sh -d [-add blockStoreAddr[=weight] | -remove blockStoreAddr] host:port"
+
+const DEBUG_NAME = "d"
+const DEBUG_USAGE = "Output log statements"
+
+const ADD_NAME = "add"
+const ADD_USAGE = "Put a BlockStore on the hash ring, optionally with its weight"
+
+const REMOVE_NAME = "remove"
+const REMOVE_USAGE = "Take a BlockStore off the hash ring, it must stay up until its blocks have moved"
+
+const ADDR_NAME = "host:port"
+const ADDR_USAGE = "IP address and port of the MetaStore (comma separated for a replicated MetaStore)"
+
+// Exit codes
+const EX_USAGE int = 64
+
+func main() {
+	// Custom flag Usage message
+	flag.Usage = func() {
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage of %s:\n", USAGE_STRING)
+		fmt.Fprintf(w, "  -%s: %v\n", DEBUG_NAME, DEBUG_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", ADD_NAME, ADD_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", REMOVE_NAME, REMOVE_USAGE)
+		fmt.Fprintf(w, "  %s: %v\n", ADDR_NAME, ADDR_USAGE)
+	}
+
+	// Parse command-line arguments and flags
+	debug := flag.Bool(DEBUG_NAME, false, DEBUG_USAGE)
+	add := flag.String(ADD_NAME, "", ADD_USAGE)
+	remove := flag.String(REMOVE_NAME, "", REMOVE_USAGE)
+	flag.Parse()
+
+	// Use tail arguments to hold non-flag arguments
+	args := flag.Args()
+
+	if len(args) != ARG_COUNT || (*add != "" && *remove != "") {
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+
+	// Disable log outputs if debug flag is missing
+	if !(*debug) {
+		log.SetFlags(0)
+		log.SetOutput(io.Discard)
+	}
+
+	rpcClient := surfstore.NewSurfstoreRPCClient(args[0], "", 0)
+	var blockStoreAddrs []string
+	var err error
+	switch {
+	case *add != "":
+		blockStoreAddr, weightStr, hasWeight := strings.Cut(*add, "=")
+		weight := 1
+		if hasWeight {
+			weight, err = strconv.Atoi(weightStr)
+			if err != nil || weight < 1 {
+				flag.Usage()
+				os.Exit(EX_USAGE)
+			}
+		}
+		err = rpcClient.AddBlockStore(context.Background(), blockStoreAddr, weight, &blockStoreAddrs)
+	case *remove != "":
+		err = rpcClient.RemoveBlockStore(context.Background(), *remove, &blockStoreAddrs)
+	default:
+		err = rpcClient.GetBlockStoreAddrs(context.Background(), &blockStoreAddrs)
+	}
+	rpcClient.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, blockStoreAddr := range blockStoreAddrs {
+		fmt.Println(blockStoreAddr)
+	}
+}
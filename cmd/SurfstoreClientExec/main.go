@@ -1,26 +1,65 @@
 package main
 
 import (
+	"context"
 	"cse224/proj4/pkg/surfstore"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 )
 
 // Arguments
 const ARG_COUNT int = 3
 
 // Usage strings
-const USAGE_STRING = "./run-client.sh -d host:port baseDir blockSize"
+const USAGE_STRING = "./run-client.sh -d -c -min minSize -max maxSize -j transfers -blocktimeout timeout -watch -poll interval -debounce delay -pull interval -history file -restore file -version n host:port baseDir blockSize"
 
 const DEBUG_NAME = "d"
 const DEBUG_USAGE = "Output log statements"
 
+const CDC_NAME = "c"
+const CDC_USAGE = "Cut files into blocks by content (FastCDC), blockSize becomes the average block size"
+
+const MIN_NAME = "min"
+const MIN_USAGE = "(default = blockSize/4) Smallest block cut with -c"
+
+const MAX_NAME = "max"
+const MAX_USAGE = "(default = blockSize*4, at most 4 MB) Largest block cut with -c"
+
+const PARALLEL_NAME = "j"
+const PARALLEL_USAGE = "Number of block transfers run at once"
+
+const BLOCK_TIMEOUT_NAME = "blocktimeout"
+const BLOCK_TIMEOUT_USAGE = "How long a BlockStore may take to send a block before the next replica is tried"
+
+const WATCH_NAME = "watch"
+const WATCH_USAGE = "Keep running and sync whenever baseDir changes, until SIGINT or SIGTERM"
+
+const POLL_NAME = "poll"
+const POLL_USAGE = "How often -watch scans baseDir for changes"
+
+const DEBOUNCE_NAME = "debounce"
+const DEBOUNCE_USAGE = "How long baseDir must stay unchanged before -watch syncs it"
+
+const PULL_NAME = "pull"
+const PULL_USAGE = "How often -watch syncs to pull remote changes"
+
+const HISTORY_NAME = "history"
+const HISTORY_USAGE = "List the versions of a file (path relative to baseDir) the MetaStore retained, instead of syncing"
+
+const RESTORE_NAME = "restore"
+const RESTORE_USAGE = "Restore a file (path relative to baseDir) to the version given by -version, then sync"
+
+const VERSION_NAME = "version"
+const VERSION_USAGE = "Version to restore with -restore"
+
 const ADDR_NAME = "host:port"
-const ADDR_USAGE = "IP address and port of the MetaStore the client is syncing to"
+const ADDR_USAGE = "IP address and port of the MetaStore the client is syncing to (comma separated for a replicated MetaStore)"
 
 const BASEDIR_NAME = "baseDir"
 const BASEDIR_USAGE = "Base directory of the client"
@@ -37,6 +76,18 @@ func main() {
 		w := flag.CommandLine.Output()
 		fmt.Fprintf(w, "Usage of %s:\n", USAGE_STRING)
 		fmt.Fprintf(w, "  -%s: %v\n", DEBUG_NAME, DEBUG_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", CDC_NAME, CDC_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", MIN_NAME, MIN_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", MAX_NAME, MAX_USAGE)
+		fmt.Fprintf(w, "  -%s: %v (default %v)\n", PARALLEL_NAME, PARALLEL_USAGE, surfstore.DEFAULT_SYNC_PARALLELISM)
+		fmt.Fprintf(w, "  -%s: %v (default %v)\n", BLOCK_TIMEOUT_NAME, BLOCK_TIMEOUT_USAGE, surfstore.DEFAULT_GET_BLOCK_TIMEOUT)
+		fmt.Fprintf(w, "  -%s: %v\n", WATCH_NAME, WATCH_USAGE)
+		fmt.Fprintf(w, "  -%s: %v (default %v)\n", POLL_NAME, POLL_USAGE, surfstore.DEFAULT_WATCH_POLL_INTERVAL)
+		fmt.Fprintf(w, "  -%s: %v (default %v)\n", DEBOUNCE_NAME, DEBOUNCE_USAGE, surfstore.DEFAULT_WATCH_DEBOUNCE)
+		fmt.Fprintf(w, "  -%s: %v (default %v)\n", PULL_NAME, PULL_USAGE, surfstore.DEFAULT_WATCH_PULL_INTERVAL)
+		fmt.Fprintf(w, "  -%s: %v\n", HISTORY_NAME, HISTORY_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", RESTORE_NAME, RESTORE_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", VERSION_NAME, VERSION_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", ADDR_NAME, ADDR_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", BASEDIR_NAME, BASEDIR_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", BLOCK_NAME, BLOCK_USAGE)
@@ -44,6 +95,18 @@ func main() {
 
 	// Parse command-line arguments and flags
 	debug := flag.Bool("d", false, DEBUG_USAGE)
+	cdc := flag.Bool(CDC_NAME, false, CDC_USAGE)
+	minSize := flag.Int(MIN_NAME, 0, MIN_USAGE)
+	maxSize := flag.Int(MAX_NAME, 0, MAX_USAGE)
+	parallelism := flag.Int(PARALLEL_NAME, surfstore.DEFAULT_SYNC_PARALLELISM, PARALLEL_USAGE)
+	blockTimeout := flag.Duration(BLOCK_TIMEOUT_NAME, surfstore.DEFAULT_GET_BLOCK_TIMEOUT, BLOCK_TIMEOUT_USAGE)
+	watch := flag.Bool(WATCH_NAME, false, WATCH_USAGE)
+	pollInterval := flag.Duration(POLL_NAME, surfstore.DEFAULT_WATCH_POLL_INTERVAL, POLL_USAGE)
+	debounce := flag.Duration(DEBOUNCE_NAME, surfstore.DEFAULT_WATCH_DEBOUNCE, DEBOUNCE_USAGE)
+	pullInterval := flag.Duration(PULL_NAME, surfstore.DEFAULT_WATCH_PULL_INTERVAL, PULL_USAGE)
+	history := flag.String(HISTORY_NAME, "", HISTORY_USAGE)
+	restore := flag.String(RESTORE_NAME, "", RESTORE_USAGE)
+	version := flag.Int(VERSION_NAME, 0, VERSION_USAGE)
 	flag.Parse()
 
 	// Use tail arguments to hold non-flag arguments
@@ -57,7 +120,17 @@ func main() {
 	hostPort := args[0]
 	baseDir := args[1]
 	blockSize, err := strconv.Atoi(args[2])
-	if err != nil {
+	if err != nil || *parallelism < 1 || *blockTimeout <= 0 {
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+	if blockSize <= 0 || blockSize > surfstore.MAX_BLOCK_SIZE {
+		fmt.Fprintf(os.Stderr, "blockSize must be between 1 and %d\n", surfstore.MAX_BLOCK_SIZE)
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+	if !*cdc && (*minSize != 0 || *maxSize != 0) {
+		fmt.Fprintf(os.Stderr, "-%s and -%s only apply with -%s\n", MIN_NAME, MAX_NAME, CDC_NAME)
 		flag.Usage()
 		os.Exit(EX_USAGE)
 	}
@@ -69,6 +142,71 @@ func main() {
 	}
 
 	rpcClient := surfstore.NewSurfstoreRPCClient(hostPort, baseDir, blockSize)
-	surfstore.ClientSync(rpcClient)
+	rpcClient.GetBlockTimeout = *blockTimeout
+	if *cdc {
+		if *minSize == 0 {
+			*minSize = blockSize / 4
+		}
+		if *maxSize == 0 {
+			*maxSize = blockSize * 4
+			if *maxSize > surfstore.MAX_BLOCK_SIZE {
+				*maxSize = surfstore.MAX_BLOCK_SIZE
+			}
+		}
+		chunker, err := surfstore.NewContentDefinedChunker(*minSize, blockSize, *maxSize)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+		rpcClient.Chunker = chunker
+	}
+	syncOptions := surfstore.SyncOptions{Client: rpcClient, Parallelism: *parallelism}
+
+	// SIGINT or SIGTERM cancels the sync, or stops -watch
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if *history != "" {
+		err = surfstore.PrintFileVersions(ctx, rpcClient, *history)
+	} else if *restore != "" {
+		if *version <= 0 {
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+		var report *surfstore.SyncReport
+		report, err = surfstore.ClientRestore(ctx, syncOptions, *restore, int32(*version))
+		if err == nil {
+			fmt.Printf("RESTORED: %s to version %d\n", *restore, *version)
+			printReport(report)
+		}
+	} else if *watch {
+		surfstore.ClientWatch(ctx, syncOptions, surfstore.WatchOptions{
+			PollInterval: *pollInterval,
+			Debounce:     *debounce,
+			PullInterval: *pullInterval,
+		})
+	} else {
+		var report *surfstore.SyncReport
+		report, err = surfstore.Sync(ctx, syncOptions)
+		if err == nil {
+			printReport(report)
+		}
+	}
+	rpcClient.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
 
+// printReport tells the user about conflicts, the rest of the report is
+// only logged
+func printReport(report *surfstore.SyncReport) {
+	for _, conflict := range report.Conflicts {
+		fmt.Printf("CONFLICT: %s was changed remotely, local changes saved as %s\n", conflict.FileName, conflict.ConflictCopy)
+	}
+	log.Println("Uploaded:", report.Uploaded)
+	log.Println("Downloaded:", report.Downloaded)
+	log.Println("Deleted:", report.Deleted)
+	log.Printf("Sent %d bytes, received %d bytes\n", report.BytesUploaded, report.BytesDownloaded)
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"cse224/proj4/pkg/surfstore"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+)
+
+// Arguments
+const ARG_COUNT int = 1
+
+// Usage strings
+const USAGE_STRING = "./run-gc.sh -d -g gracePeriod host:port"
+
+const DEBUG_NAME = "d"
+const DEBUG_USAGE = "Output log statements"
+
+const GRACE_NAME = "g"
+const GRACE_USAGE = "Keep unreferenced blocks stored more recently than this, they may belong to a sync in progress"
+
+const ADDR_NAME = "host:port"
+const ADDR_USAGE = "IP address and port of the MetaStore (comma separated for a replicated MetaStore)"
+
+// Exit codes
+const EX_USAGE int = 64
+
+func main() {
+	// Custom flag Usage message
+	flag.Usage = func() {
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage of %s:\n", USAGE_STRING)
+		fmt.Fprintf(w, "  -%s: %v\n", DEBUG_NAME, DEBUG_USAGE)
+		fmt.Fprintf(w, "  -%s: %v (default %v)\n", GRACE_NAME, GRACE_USAGE, surfstore.DEFAULT_GC_GRACE_PERIOD)
+		fmt.Fprintf(w, "  %s: %v\n", ADDR_NAME, ADDR_USAGE)
+	}
+
+	// Parse command-line arguments and flags
+	debug := flag.Bool(DEBUG_NAME, false, DEBUG_USAGE)
+	gracePeriod := flag.Duration(GRACE_NAME, surfstore.DEFAULT_GC_GRACE_PERIOD, GRACE_USAGE)
+	flag.Parse()
+
+	// Use tail arguments to hold non-flag arguments
+	args := flag.Args()
+
+	if len(args) != ARG_COUNT || *gracePeriod < 0 {
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+
+	// Disable log outputs if debug flag is missing
+	if !(*debug) {
+		log.SetFlags(0)
+		log.SetOutput(io.Discard)
+	}
+
+	rpcClient := surfstore.NewSurfstoreRPCClient(args[0], "", 0)
+	deleted, err := surfstore.CollectGarbage(context.Background(), rpcClient, *gracePeriod)
+	printDeleted(deleted)
+	rpcClient.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func printDeleted(deleted map[string][]string) {
+	blockStoreAddrs := make([]string, 0, len(deleted))
+	for blockStoreAddr := range deleted {
+		blockStoreAddrs = append(blockStoreAddrs, blockStoreAddr)
+	}
+	sort.Strings(blockStoreAddrs)
+	for _, blockStoreAddr := range blockStoreAddrs {
+		fmt.Printf("%s: deleted %d blocks\n", blockStoreAddr, len(deleted[blockStoreAddr]))
+	}
+}
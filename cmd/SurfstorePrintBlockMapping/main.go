@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"cse224/proj4/pkg/surfstore"
 	"flag"
 	"fmt"
@@ -14,11 +15,14 @@ import (
 const ARG_COUNT int = 3
 
 // Usage strings
-const USAGE_STRING = "./run-client.sh -d host:port baseDir blockSize"
+const USAGE_STRING = "./run-client.sh -d -dist host:port baseDir blockSize"
 
 const DEBUG_NAME = "d"
 const DEBUG_USAGE = "Output log statements"
 
+const DIST_NAME = "dist"
+const DIST_USAGE = "Also print how many blocks each server holds"
+
 const ADDR_NAME = "host:port"
 const ADDR_USAGE = "IP address and port of the MetaStore the client is syncing to"
 
@@ -37,6 +41,7 @@ func main() {
 		w := flag.CommandLine.Output()
 		fmt.Fprintf(w, "Usage of %s:\n", USAGE_STRING)
 		fmt.Fprintf(w, "  -%s: %v\n", DEBUG_NAME, DEBUG_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", DIST_NAME, DIST_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", ADDR_NAME, ADDR_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", BASEDIR_NAME, BASEDIR_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", BLOCK_NAME, BLOCK_USAGE)
@@ -44,6 +49,7 @@ func main() {
 
 	// Parse command-line arguments and flags
 	debug := flag.Bool("d", false, DEBUG_USAGE)
+	dist := flag.Bool(DIST_NAME, false, DIST_USAGE)
 	flag.Parse()
 
 	// Use tail arguments to hold non-flag arguments
@@ -69,23 +75,26 @@ func main() {
 	}
 
 	rpcClient := surfstore.NewSurfstoreRPCClient(hostPort, baseDir, blockSize)
-	PrintBlocksOnEachServer(rpcClient)
+	PrintBlocksOnEachServer(rpcClient, *dist)
+	rpcClient.Close()
 }
 
-func PrintBlocksOnEachServer(client surfstore.RPCClient) {
+func PrintBlocksOnEachServer(client *surfstore.RPCClient, showDistribution bool) {
 	allAddrs := []string{}
-	err := client.GetBlockStoreAddrs(&allAddrs)
+	err := client.GetBlockStoreAddrs(context.Background(), &allAddrs)
 	if err != nil {
 		log.Fatal("[Surfstore RPCClient]:", "Error During Fetching All BlockStore Addresses ", err)
 	}
 
 	result := "{"
-	for _, addr := range allAddrs {
+	blockCounts := make([]int, len(allAddrs))
+	for i, addr := range allAddrs {
 		// fmt.Println("Block Server: ", addr)
 		hashes := []string{}
-		if err = client.GetBlockHashes(addr, &hashes); err != nil {
+		if err = client.GetBlockHashes(context.Background(), addr, &hashes); err != nil {
 			log.Fatal("[Surfstore RPCClient]:", "Error During Fetching Blocks on Block Server ", err)
 		}
+		blockCounts[i] = len(hashes)
 
 		for _, hash := range hashes {
 			result += "{" + hash + "," + addr + "},"
@@ -97,4 +106,23 @@ func PrintBlocksOnEachServer(client surfstore.RPCClient) {
 		result = result[:len(result)-1] + "}"
 	}
 	fmt.Println(result)
+
+	if showDistribution {
+		printDistribution(allAddrs, blockCounts)
+	}
+}
+
+// printDistribution prints each server's share of the blocks
+func printDistribution(addrs []string, blockCounts []int) {
+	total := 0
+	for _, count := range blockCounts {
+		total += count
+	}
+	for i, addr := range addrs {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(blockCounts[i]) / float64(total)
+		}
+		fmt.Printf("%s: %d blocks (%.1f%%)\n", addr, blockCounts[i], share)
+	}
 }
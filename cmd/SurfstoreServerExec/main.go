@@ -15,11 +15,14 @@ import (
 )
 
 // Usage String
-const USAGE_STRING = "./run-server.sh -s <service_type> -p <port> -l -d (blockStoreAddr*)"
+const USAGE_STRING = "./run-server.sh -s <service_type> -p <port> -l -d -t <storage> -b <blockDir> -m <metaDir> -r <raftAddrs> -i <raftId> -v <versions> -n <virtualNodes> -f <replicas> (blockStoreAddr[=weight]*)"
 
 // Set of valid services
 var SERVICE_TYPES = map[string]bool{"meta": true, "block": true, "both": true}
 
+// Set of valid block storage backends
+var STORAGE_TYPES = map[string]bool{"memory": true, "fs": true, "sqlite": true}
+
 // Exit codes
 const EX_USAGE int = 64
 
@@ -31,7 +34,7 @@ func main() {
 		flag.VisitAll(func(f *flag.Flag) {
 			fmt.Fprintf(w, "  -%s: %v\n", f.Name, f.Usage)
 		})
-		fmt.Fprintf(w, "  (blockStoreAddr*): BlockStore Address (include self if service type is both)\n")
+		fmt.Fprintf(w, "  (blockStoreAddr[=weight]*): BlockStore Address (include self if service type is both), optionally with its weight on the hash ring\n")
 	}
 
 	// Parse command-line argument flags
@@ -39,14 +42,32 @@ func main() {
 	port := flag.Int("p", 8080, "(default = 8080) Port to accept connections")
 	localOnly := flag.Bool("l", false, "Only listen on localhost")
 	debug := flag.Bool("d", false, "Output log statements")
+	storage := flag.String("t", "", "(default = memory, or fs if -b is set) Block storage backend: memory, fs, sqlite")
+	blockDir := flag.String("b", "", "Directory the fs and sqlite block storage backends persist blocks in")
+	metaDir := flag.String("m", "", "(default = in memory) Directory to persist the MetaStore log and snapshots in")
+	raftAddrs := flag.String("r", "", "Comma separated addresses of every MetaStore replica, replicates the MetaStore with Raft")
+	raftId := flag.Int("i", 0, "(default = 0) Index of this server's address in -r")
+	historySize := flag.Int("v", surfstore.DEFAULT_FILE_HISTORY_SIZE, fmt.Sprintf("(default = %d) Number of previous versions of each file the MetaStore keeps", surfstore.DEFAULT_FILE_HISTORY_SIZE))
+	virtualNodes := flag.Int("n", 1, "(default = 1) Points each BlockStore gets on the hash ring per unit of weight")
+	replicationFactor := flag.Int("f", 1, "(default = 1) Number of BlockStores each block is stored on")
 	flag.Parse()
 
 	// Use tail arguments to hold BlockStore address
 	args := flag.Args()
 	blockStoreAddrs := []string{}
+	blockStoreWeights := map[string]int{}
 	for _, arg := range args {
-		blockStoreAddrs = append(blockStoreAddrs, arg)
+		blockStoreAddr, weight, err := parseBlockStoreArg(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+		blockStoreAddrs = append(blockStoreAddrs, blockStoreAddr)
+		blockStoreWeights[blockStoreAddr] = weight
 	}
+	ring := surfstore.NewWeightedConsistentHashRing(blockStoreAddrs, blockStoreWeights, *virtualNodes)
+	ring.ReplicationFactor = *replicationFactor
 
 	// Valid service type argument
 	if _, ok := SERVICE_TYPES[strings.ToLower(*service)]; !ok {
@@ -54,6 +75,35 @@ func main() {
 		os.Exit(EX_USAGE)
 	}
 
+	// Valid block storage backend
+	storageType := strings.ToLower(*storage)
+	if storageType == "" {
+		storageType = "memory"
+		if *blockDir != "" {
+			storageType = "fs"
+		}
+	}
+	if !STORAGE_TYPES[storageType] || (storageType != "memory" && *blockDir == "") {
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+
+	// Valid history size and ring
+	if *historySize < 0 || *virtualNodes < 1 || *replicationFactor < 1 {
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+
+	// Valid raft replica index
+	raftPeers := []string{}
+	if *raftAddrs != "" {
+		raftPeers = strings.Split(*raftAddrs, surfstore.CONFIG_DELIMITER)
+		if *raftId < 0 || *raftId >= len(raftPeers) {
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+	}
+
 	// Add localhost if necessary
 	addr := ""
 	if *localOnly {
@@ -67,15 +117,68 @@ func main() {
 		log.SetOutput(io.Discard)
 	}
 
-	log.Fatal(startServer(addr, strings.ToLower(*service), blockStoreAddrs))
+	log.Fatal(startServer(addr, strings.ToLower(*service), blockStoreAddrs, ring, storageType, *blockDir, *metaDir, raftPeers, int64(*raftId), *historySize))
+}
+
+// parseBlockStoreArg splits a blockStoreAddr[=weight] argument
+func parseBlockStoreArg(arg string) (string, int, error) {
+	blockStoreAddr, weightStr, hasWeight := strings.Cut(arg, "=")
+	if !hasWeight {
+		return blockStoreAddr, 1, nil
+	}
+	weight, err := strconv.Atoi(weightStr)
+	if err != nil || weight < 1 {
+		return "", 0, fmt.Errorf("Invalid weight in %q", arg)
+	}
+	return blockStoreAddr, weight, nil
+}
+
+// registerMetaStore registers a plain MetaStore, or a Raft replica of one
+// when raftPeers is not empty, on server
+func registerMetaStore(server *grpc.Server, blockStoreAddrs []string, ring *surfstore.ConsistentHashRing, metaDir string, raftPeers []string, raftId int64, historySize int) error {
+	if len(raftPeers) == 0 {
+		metasrv, err := surfstore.NewMetaStore(blockStoreAddrs, metaDir, historySize)
+		if err != nil {
+			return err
+		}
+		metasrv.SetConsistentHashRing(ring)
+		surfstore.RegisterMetaStoreServer(server, metasrv)
+		return nil
+	}
+	raftsrv, err := surfstore.NewRaftServer(raftId, raftPeers, blockStoreAddrs, metaDir, historySize)
+	if err != nil {
+		return err
+	}
+	raftsrv.SetConsistentHashRing(ring)
+	surfstore.RegisterMetaStoreServer(server, raftsrv)
+	surfstore.RegisterRaftSurfstoreServer(server, raftsrv)
+	return nil
+}
+
+func newBlockStore(storageType string, blockDir string) (*surfstore.BlockStore, error) {
+	var storage surfstore.BlockStorage
+	var err error
+	switch storageType {
+	case "fs":
+		storage, err = surfstore.NewFileBlockStorage(blockDir)
+	case "sqlite":
+		storage, err = surfstore.NewSQLiteBlockStorage(blockDir)
+	default:
+		storage = surfstore.NewMemoryBlockStorage()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return surfstore.NewBlockStoreWithStorage(storage), nil
 }
 
-func startServer(hostAddr string, serviceType string, blockStoreAddrs []string) error {
+func startServer(hostAddr string, serviceType string, blockStoreAddrs []string, ring *surfstore.ConsistentHashRing, storageType string, blockDir string, metaDir string, raftPeers []string, raftId int64, historySize int) error {
 	// start servers depending on service type
 	if serviceType == "meta" {
 		metaServer := grpc.NewServer()
-		metasrv := surfstore.NewMetaStore(blockStoreAddrs)
-		surfstore.RegisterMetaStoreServer(metaServer, metasrv)
+		if err := registerMetaStore(metaServer, blockStoreAddrs, ring, metaDir, raftPeers, raftId, historySize); err != nil {
+			return err
+		}
 		l, err := net.Listen("tcp", hostAddr)
 		if err != nil {
 			return err
@@ -85,7 +188,10 @@ func startServer(hostAddr string, serviceType string, blockStoreAddrs []string)
 		return metaServer.Serve(l)
 	} else if serviceType == "block" {
 		blockServer := grpc.NewServer()
-		blocksrv := surfstore.NewBlockStore()
+		blocksrv, err := newBlockStore(storageType, blockDir)
+		if err != nil {
+			return err
+		}
 		surfstore.RegisterBlockStoreServer(blockServer, blocksrv)
 		l, err := net.Listen("tcp", hostAddr)
 		if err != nil {
@@ -96,9 +202,13 @@ func startServer(hostAddr string, serviceType string, blockStoreAddrs []string)
 		return blockServer.Serve(l)
 	}
 	doubleServer := grpc.NewServer()
-	metasrv := surfstore.NewMetaStore(blockStoreAddrs)
-	surfstore.RegisterMetaStoreServer(doubleServer, metasrv)
-	blocksrv := surfstore.NewBlockStore()
+	if err := registerMetaStore(doubleServer, blockStoreAddrs, ring, metaDir, raftPeers, raftId, historySize); err != nil {
+		return err
+	}
+	blocksrv, err := newBlockStore(storageType, blockDir)
+	if err != nil {
+		return err
+	}
 	surfstore.RegisterBlockStoreServer(doubleServer, blocksrv)
 	l, err := net.Listen("tcp", hostAddr)
 	if err != nil {
@@ -3,13 +3,13 @@ module cse224/proj4
 go 1.22
 
 require (
+	github.com/mattn/go-sqlite3 v1.14.16
 	google.golang.org/grpc v1.44.0
 	google.golang.org/protobuf v1.27.1
 )
 
 require (
 	github.com/golang/protobuf v1.5.0 // indirect
-	github.com/mattn/go-sqlite3 v1.14.16 // indirect
 	golang.org/x/net v0.0.0-20200822124328-c89045814202 // indirect
 	golang.org/x/sys v0.0.0-20200323222414-85ca7c5b95cd // indirect
 	golang.org/x/text v0.3.0 // indirect
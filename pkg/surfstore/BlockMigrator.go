@@ -0,0 +1,282 @@
+package surfstore
+
+import (
+	context "context"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blockMigrator moves blocks onto the BlockStores the hash ring places them
+// on after BlockStores were added or removed. Until a migration finishes
+// the rings blocks were placed by before are kept as draining rings, and
+// reads fall back to the BlockStores those rings place a block on.
+//
+// A migration pass lists the blocks on every BlockStore of the current and
+// draining rings. Each block is copied to the replicas the current ring
+// picks that lack it and then deleted from the BlockStores that are no
+// longer its replicas, blocks whose replicas did not change are left alone.
+// Deletes wait until deleteDelay after the ring changed so clients that
+// looked blocks up before the change can still read them.
+// Passes repeat until one finds nothing to do, which also moves blocks
+// uploaded by clients that were still using the previous ring.
+type blockMigrator struct {
+	mtx        sync.Mutex
+	client     *RPCClient
+	ring       *ConsistentHashRing
+	draining   []*ConsistentHashRing
+	changedAt  time.Time
+	generation int
+	running    bool
+	// BLOCK_MIGRATION_DELETE_DELAY outside of tests
+	deleteDelay time.Duration
+	// reports whether blocks should be migrated from this MetaStore, nil
+	// for always
+	active func() bool
+}
+
+func newBlockMigrator() *blockMigrator {
+	return &blockMigrator{client: NewSurfstoreRPCClient("", "", 0), deleteDelay: BLOCK_MIGRATION_DELETE_DELAY}
+}
+
+// ringChanged records that blocks are placed by ring from now on, previous
+// becomes a draining ring
+func (bm *blockMigrator) ringChanged(previous *ConsistentHashRing, ring *ConsistentHashRing) {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+	bm.draining = append(bm.draining, previous)
+	bm.ring = ring
+	bm.changedAt = time.Now()
+	bm.generation++
+}
+
+// restore brings back the rings of a migration that was cut short by a
+// restart
+func (bm *blockMigrator) restore(draining []*ConsistentHashRing, ring *ConsistentHashRing) {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+	bm.draining = draining
+	bm.ring = ring
+	bm.changedAt = time.Now()
+	bm.generation++
+}
+
+// drainingMemberships describes the draining rings, oldest first
+func (bm *blockMigrator) drainingMemberships() []*BlockStoreMembership {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+	memberships := make([]*BlockStoreMembership, 0, len(bm.draining))
+	for _, ring := range bm.draining {
+		memberships = append(memberships, ring.membership(ring.servers()))
+	}
+	return memberships
+}
+
+// readReplicas returns the BlockStores to read a block from: its replicas,
+// followed by the BlockStores the draining rings placed it on, newest ring
+// first
+func (bm *blockMigrator) readReplicas(blockHash string, replicas []string) []string {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+	if len(bm.draining) == 0 {
+		return replicas
+	}
+	readFrom := append([]string{}, replicas...)
+	for i := len(bm.draining) - 1; i >= 0; i-- {
+		for _, blockStoreAddr := range bm.draining[i].GetResponsibleServers(blockHash) {
+			if !contains(readFrom, blockStoreAddr) {
+				readFrom = append(readFrom, blockStoreAddr)
+			}
+		}
+	}
+	return readFrom
+}
+
+// start runs migration passes in the background until there are no
+// draining rings left, unless they are running already
+func (bm *blockMigrator) start() {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+	if bm.running || len(bm.draining) == 0 {
+		return
+	}
+	bm.running = true
+	go bm.run()
+}
+
+func (bm *blockMigrator) run() {
+	for {
+		// outside of bm.mtx, active may take the Raft server's lock
+		if bm.active != nil && !bm.active() {
+			bm.mtx.Lock()
+			bm.running = false
+			bm.mtx.Unlock()
+			return
+		}
+
+		bm.mtx.Lock()
+		generation := bm.generation
+		ring := bm.ring
+		changedAt := bm.changedAt
+		// BlockStores to scan, true for the ones still on the ring
+		blockStores := make(map[string]bool)
+		for _, draining := range bm.draining {
+			for _, blockStoreAddr := range draining.servers() {
+				blockStores[blockStoreAddr] = false
+			}
+		}
+		for _, blockStoreAddr := range ring.servers() {
+			blockStores[blockStoreAddr] = true
+		}
+		bm.mtx.Unlock()
+
+		moved, err := bm.migrate(context.Background(), ring, blockStores, changedAt, generation)
+		if err != nil {
+			log.Println("Error migrating blocks:", err)
+			time.Sleep(BLOCK_MIGRATION_RETRY_INTERVAL)
+			continue
+		}
+
+		bm.mtx.Lock()
+		if !moved && generation == bm.generation {
+			log.Println("Block migration finished")
+			bm.draining = nil
+			bm.running = false
+			bm.mtx.Unlock()
+			return
+		}
+		bm.mtx.Unlock()
+	}
+}
+
+// migrate makes one pass over blockStores, moving blocks to where ring
+// places them, and reports whether it moved anything
+func (bm *blockMigrator) migrate(ctx context.Context, ring *ConsistentHashRing, blockStores map[string]bool, changedAt time.Time, generation int) (bool, error) {
+	holders := make(map[string][]string)
+	for blockStoreAddr, onRing := range blockStores {
+		var hashes []string
+		if err := bm.client.GetBlockHashes(ctx, blockStoreAddr, &hashes); err != nil {
+			if onRing {
+				return false, err
+			}
+			// a removed BlockStore that is gone for good, its blocks
+			// can only be recovered from other replicas
+			log.Printf("Skipping unreachable BlockStore %s: %v\n", blockStoreAddr, err)
+			continue
+		}
+		for _, hash := range hashes {
+			holders[hash] = append(holders[hash], blockStoreAddr)
+		}
+	}
+
+	type copyRoute struct{ from, to string }
+	copies := make(map[copyRoute][]string)
+	deletes := make(map[string][]string)
+	for hash, holdingAddrs := range holders {
+		replicas := ring.GetResponsibleServers(hash)
+		for _, replica := range replicas {
+			if !contains(holdingAddrs, replica) {
+				route := copyRoute{from: holdingAddrs[0], to: replica}
+				copies[route] = append(copies[route], hash)
+			}
+		}
+		for _, holdingAddr := range holdingAddrs {
+			if !contains(replicas, holdingAddr) {
+				deletes[holdingAddr] = append(deletes[holdingAddr], hash)
+			}
+		}
+	}
+	if len(copies) == 0 && len(deletes) == 0 {
+		return false, nil
+	}
+
+	moved := false
+	// blocks that could not be copied stay where they are
+	notCopied := make(map[string]bool)
+	for route, hashes := range copies {
+		for start := 0; start < len(hashes); start += BLOCK_MIGRATION_BATCH_SIZE {
+			end := start + BLOCK_MIGRATION_BATCH_SIZE
+			if end > len(hashes) {
+				end = len(hashes)
+			}
+			batch := hashes[start:end]
+			var blocks []*Block
+			err := bm.client.GetBlocks(ctx, batch, route.from, &blocks)
+			if status.Code(err) == codes.DataLoss {
+				// the BlockStore refuses to serve a corrupt block, read the
+				// batch a block at a time to copy the others
+				blocks, err = bm.getEachBlock(ctx, batch, route.from)
+			}
+			if err != nil {
+				return moved, err
+			}
+			intact := make([]*Block, 0, len(blocks))
+			for i, block := range blocks {
+				if block == nil || GetBlockHashString(block.BlockData) != batch[i] {
+					log.Printf("Not migrating corrupt block %s on %s\n", batch[i], route.from)
+					notCopied[batch[i]] = true
+					continue
+				}
+				intact = append(intact, block)
+			}
+			if len(intact) == 0 {
+				continue
+			}
+			var succ bool
+			if err := bm.client.PutBlocks(ctx, intact, route.to, &succ); err != nil {
+				return moved, err
+			}
+			moved = true
+		}
+	}
+
+	if wait := time.Until(changedAt.Add(bm.deleteDelay)); wait > 0 {
+		time.Sleep(wait)
+	}
+	bm.mtx.Lock()
+	ringChanged := generation != bm.generation
+	bm.mtx.Unlock()
+	if ringChanged {
+		// the blocks may belong where they are again, start over
+		return true, nil
+	}
+	for blockStoreAddr, hashes := range deletes {
+		toDelete := make([]string, 0, len(hashes))
+		for _, hash := range hashes {
+			if !notCopied[hash] {
+				toDelete = append(toDelete, hash)
+			}
+		}
+		if len(toDelete) == 0 {
+			continue
+		}
+		var deleted []string
+		if err := bm.client.DeleteBlocks(ctx, toDelete, 0, blockStoreAddr, &deleted); err != nil {
+			return moved, err
+		}
+		if len(deleted) > 0 {
+			moved = true
+		}
+	}
+	return moved, nil
+}
+
+// getEachBlock reads hashes from blockStoreAddr one at a time, a block the
+// BlockStore reports as corrupt is left nil
+func (bm *blockMigrator) getEachBlock(ctx context.Context, hashes []string, blockStoreAddr string) ([]*Block, error) {
+	blocks := make([]*Block, len(hashes))
+	for i, hash := range hashes {
+		block := &Block{}
+		if err := bm.client.GetBlock(ctx, hash, blockStoreAddr, block); err != nil {
+			if status.Code(err) == codes.DataLoss {
+				continue
+			}
+			return nil, err
+		}
+		blocks[i] = block
+	}
+	return blocks, nil
+}
@@ -0,0 +1,211 @@
+package surfstore
+
+import (
+	context "context"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	grpc "google.golang.org/grpc"
+)
+
+const testDeleteDelay = 200 * time.Millisecond
+
+// startMemoryBlockStores serves n BlockStores and returns their addresses
+// and storage
+func startMemoryBlockStores(t *testing.T, n int) ([]string, []*MemoryBlockStorage) {
+	t.Helper()
+	addrs := make([]string, n)
+	storages := make([]*MemoryBlockStorage, n)
+	for i := range addrs {
+		storage := NewMemoryBlockStorage()
+		storages[i] = storage
+		addrs[i] = startTestServer(t, func(server *grpc.Server, _ string) {
+			RegisterBlockStoreServer(server, NewBlockStoreWithStorage(storage))
+		})
+	}
+	return addrs, storages
+}
+
+func (bm *blockMigrator) isRunning() bool {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+	return bm.running
+}
+
+// assertPlacement checks every block in hashes is on exactly the replicas
+// ring picks for it
+func assertPlacement(t *testing.T, ring *ConsistentHashRing, addrs []string, storages []*MemoryBlockStorage, hashes []string) {
+	t.Helper()
+	for _, hash := range hashes {
+		holders := []string{}
+		for i, storage := range storages {
+			if found, _ := storage.Has(hash); found {
+				holders = append(holders, addrs[i])
+			}
+		}
+		replicas := ring.GetResponsibleServers(hash)
+		sort.Strings(holders)
+		sort.Strings(replicas)
+		if len(holders) != len(replicas) {
+			t.Fatalf("block %s is on %v, want %v", hash, holders, replicas)
+		}
+		for i := range replicas {
+			if holders[i] != replicas[i] {
+				t.Fatalf("block %s is on %v, want %v", hash, holders, replicas)
+			}
+		}
+	}
+}
+
+// After a BlockStore is added and another removed every block ends up on
+// exactly its replicas, and clients can read every block throughout
+func TestMigrationAfterMembershipChanges(t *testing.T) {
+	addrs, storages := startMemoryBlockStores(t, 3)
+	metaStore, err := NewMetaStore(addrs[:2], "", DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metaStore.migrator.deleteDelay = testDeleteDelay
+	metaAddr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterMetaStoreServer(server, metaStore)
+	})
+	baseDir := t.TempDir()
+	data := make([]byte, 4*200)
+	rand.New(rand.NewSource(1)).Read(data)
+	if err := os.WriteFile(ConcatPath(baseDir, "f.bin"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	syncDir(t, metaAddr, baseDir)
+	hashes := metaStore.FileMetaMap["f.bin"].BlockHashList
+	client := NewSurfstoreRPCClient(metaAddr, "", 0)
+	defer client.Close()
+
+	// a client downloading the file over and over
+	stop := make(chan struct{})
+	readErr := make(chan error, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			hashToData, err := fetchBlocks(context.Background(), client, 4, hashes)
+			if err == nil && len(hashToData) != len(hashes) {
+				err = &BlockCorruptionError{Hash: "missing blocks"}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	changes := []func() error{
+		func() error {
+			var blockStoreAddrs []string
+			return client.AddBlockStore(context.Background(), addrs[2], 1, &blockStoreAddrs)
+		},
+		func() error {
+			var blockStoreAddrs []string
+			return client.RemoveBlockStore(context.Background(), addrs[0], &blockStoreAddrs)
+		},
+	}
+	for _, change := range changes {
+		if err := change(); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, "the blocks are migrated", func() bool {
+			return !metaStore.migrator.isRunning()
+		})
+		ring, _ := metaStore.hashRing()
+		assertPlacement(t, ring, addrs, storages, hashes)
+	}
+	close(stop)
+	wg.Wait()
+	select {
+	case err := <-readErr:
+		t.Fatalf("a block could not be read during the migration: %v", err)
+	default:
+	}
+	if hashList, _ := storages[0].List(); len(hashList) != 0 {
+		t.Fatalf("%d blocks left on the removed BlockStore", len(hashList))
+	}
+}
+
+// migratorBetween places blocks by a ring of to, draining a ring of from
+func migratorBetween(from []string, to []string) *blockMigrator {
+	bm := newBlockMigrator()
+	bm.deleteDelay = testDeleteDelay
+	bm.ring = NewConsistentHashRing(from)
+	bm.ringChanged(bm.ring, NewConsistentHashRing(to))
+	return bm
+}
+
+// A pass that sees the ring change while it waits to delete starts over
+// without deleting anything
+func TestMigrationRestartsOnRingChange(t *testing.T) {
+	addrs, storages := startMemoryBlockStores(t, 2)
+	bm := migratorBetween(addrs[:1], addrs[1:])
+	defer bm.client.Close()
+	hashes := putTestBlocks(t, bm.client, addrs[0], "first block", "second block")
+
+	bm.mtx.Lock()
+	ring, changedAt, generation := bm.ring, bm.changedAt, bm.generation
+	bm.mtx.Unlock()
+	done := make(chan bool)
+	go func() {
+		moved, err := bm.migrate(context.Background(), ring, map[string]bool{addrs[0]: false, addrs[1]: true}, changedAt, generation)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- moved
+	}()
+	time.Sleep(testDeleteDelay / 2)
+	bm.ringChanged(ring, NewConsistentHashRing(addrs))
+	if !<-done {
+		t.Fatal("a pass cut short by a ring change reported nothing to do")
+	}
+	for _, hash := range hashes {
+		if found, _ := storages[0].Has(hash); !found {
+			t.Fatalf("block %s was deleted after the ring changed", hash)
+		}
+	}
+}
+
+// A block that is corrupt on the BlockStore it would be copied from is
+// neither copied nor deleted there
+func TestMigrationKeepsCorruptBlock(t *testing.T) {
+	addrs, storages := startMemoryBlockStores(t, 2)
+	bm := migratorBetween(addrs[:1], addrs[1:])
+	defer bm.client.Close()
+	intact := putTestBlocks(t, bm.client, addrs[0], "intact block")
+	corruptHash, _ := testBlock("corrupt block")
+	if err := storages[0].Put(corruptHash, &Block{BlockData: []byte("bit rot"), BlockSize: 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	bm.changedAt = time.Now().Add(-testDeleteDelay)
+	if _, err := bm.migrate(context.Background(), bm.ring, map[string]bool{addrs[0]: false, addrs[1]: true}, bm.changedAt, bm.generation); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := storages[0].Has(corruptHash); !found {
+		t.Fatal("the corrupt block was deleted from the only BlockStore holding it")
+	}
+	if found, _ := storages[1].Has(corruptHash); found {
+		t.Fatal("the corrupt block was copied")
+	}
+	if found, _ := storages[1].Has(intact[0]); !found {
+		t.Fatal("the intact block was not copied")
+	}
+	if found, _ := storages[0].Has(intact[0]); found {
+		t.Fatal("the intact block was not deleted from its old BlockStore")
+	}
+}
@@ -0,0 +1,142 @@
+package surfstore
+
+import (
+	"sort"
+	"testing"
+)
+
+// blockStorageBackend opens a BlockStorage over a directory. reopen closes
+// the storage and opens it again over the same directory, the memory
+// backend has nothing to reopen and hands back the same storage.
+type blockStorageBackend struct {
+	open   func(blockDir string) (BlockStorage, error)
+	reopen func(t *testing.T, storage BlockStorage, blockDir string) BlockStorage
+}
+
+func reopenBlockStorage(open func(blockDir string) (BlockStorage, error)) func(t *testing.T, storage BlockStorage, blockDir string) BlockStorage {
+	return func(t *testing.T, storage BlockStorage, blockDir string) BlockStorage {
+		t.Helper()
+		if s, ok := storage.(*SQLiteBlockStorage); ok {
+			if err := s.db.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		reopened, err := open(blockDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reopened
+	}
+}
+
+var blockStorageBackends = map[string]blockStorageBackend{
+	"memory": {
+		open: func(string) (BlockStorage, error) { return NewMemoryBlockStorage(), nil },
+		reopen: func(_ *testing.T, storage BlockStorage, _ string) BlockStorage {
+			return storage
+		},
+	},
+	"fs": {
+		open:   func(blockDir string) (BlockStorage, error) { return NewFileBlockStorage(blockDir) },
+		reopen: reopenBlockStorage(func(blockDir string) (BlockStorage, error) { return NewFileBlockStorage(blockDir) }),
+	},
+	"sqlite": {
+		open:   func(blockDir string) (BlockStorage, error) { return NewSQLiteBlockStorage(blockDir) },
+		reopen: reopenBlockStorage(func(blockDir string) (BlockStorage, error) { return NewSQLiteBlockStorage(blockDir) }),
+	},
+}
+
+func testBlock(data string) (string, *Block) {
+	return GetBlockHashString([]byte(data)), &Block{BlockData: []byte(data), BlockSize: int32(len(data))}
+}
+
+func assertStored(t *testing.T, storage BlockStorage, want map[string]*Block) {
+	t.Helper()
+	for hash, block := range want {
+		found, err := storage.Has(hash)
+		if err != nil || !found {
+			t.Fatalf("Has(%s) = %v, %v, want true", hash, found, err)
+		}
+		got, err := storage.Get(hash)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", hash, err)
+		}
+		if string(got.BlockData) != string(block.BlockData) || got.BlockSize != block.BlockSize {
+			t.Fatalf("Get(%s) = %q (%d bytes), want %q", hash, got.BlockData, got.BlockSize, block.BlockData)
+		}
+	}
+	hashes, err := storage.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHashes := []string{}
+	for hash := range want {
+		wantHashes = append(wantHashes, hash)
+	}
+	sort.Strings(hashes)
+	sort.Strings(wantHashes)
+	if len(hashes) != len(wantHashes) {
+		t.Fatalf("List() = %v, want %v", hashes, wantHashes)
+	}
+	for i := range hashes {
+		if hashes[i] != wantHashes[i] {
+			t.Fatalf("List() = %v, want %v", hashes, wantHashes)
+		}
+	}
+}
+
+func assertNotStored(t *testing.T, storage BlockStorage, hash string) {
+	t.Helper()
+	found, err := storage.Has(hash)
+	if err != nil || found {
+		t.Fatalf("Has(%s) = %v, %v, want false", hash, found, err)
+	}
+	if _, err := storage.Get(hash); err != ERR_BLOCK_NOT_FOUND {
+		t.Fatalf("Get(%s) = %v, want ERR_BLOCK_NOT_FOUND", hash, err)
+	}
+}
+
+// Every backend behaves the same through Put, Get, Has, Delete and List,
+// and the persistent ones keep what they stored across a reopen
+func TestBlockStorageContract(t *testing.T) {
+	for name, backend := range blockStorageBackends {
+		t.Run(name, func(t *testing.T) {
+			blockDir := t.TempDir()
+			storage, err := backend.open(blockDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStored(t, storage, map[string]*Block{})
+			firstHash, first := testBlock("first block")
+			secondHash, second := testBlock("second block")
+			emptyHash, empty := testBlock("")
+			assertNotStored(t, storage, firstHash)
+
+			// the second put of first is a no-op
+			for _, put := range []struct {
+				hash  string
+				block *Block
+			}{{firstHash, first}, {secondHash, second}, {emptyHash, empty}, {firstHash, first}} {
+				if err := storage.Put(put.hash, put.block); err != nil {
+					t.Fatalf("Put(%s): %v", put.hash, err)
+				}
+			}
+			assertStored(t, storage, map[string]*Block{firstHash: first, secondHash: second, emptyHash: empty})
+
+			storage = backend.reopen(t, storage, blockDir)
+			assertStored(t, storage, map[string]*Block{firstHash: first, secondHash: second, emptyHash: empty})
+
+			for _, hash := range []string{secondHash, secondHash} {
+				if err := storage.Delete(hash); err != nil {
+					t.Fatalf("Delete(%s): %v", hash, err)
+				}
+			}
+			assertNotStored(t, storage, secondHash)
+			assertStored(t, storage, map[string]*Block{firstHash: first, emptyHash: empty})
+
+			storage = backend.reopen(t, storage, blockDir)
+			assertNotStored(t, storage, secondHash)
+			assertStored(t, storage, map[string]*Block{firstHash: first, emptyHash: empty})
+		})
+	}
+}
@@ -2,19 +2,27 @@ package surfstore
 
 import (
 	context "context"
-	"fmt"
+	"io"
+	"sync"
+	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
+// BlockStore serves blocks from a BlockStorage, which must be safe for
+// concurrent use
 type BlockStore struct {
-	BlockMap map[string]*Block
+	BlockStorage BlockStorage
+	// DeleteBlocks holds gcMtx exclusively, so a block cannot be deleted
+	// between MissingBlocks reporting it present and touching it
+	gcMtx sync.RWMutex
 	UnimplementedBlockStoreServer
 }
 
 func (bs *BlockStore) GetBlock(ctx context.Context, blockHash *BlockHash) (*Block, error) { //MY CODE
 	// fmt.Println("BLOCKSTORE.GETBLOCK: Getting block with hash: ", blockHash.Hash)
-	val, ok := bs.BlockMap[blockHash.Hash]
 	if blockHash.Hash == "-1" {
 		emptyBlock := &Block{BlockData: []byte{}, BlockSize: -1}
 		// fmt.Println("BLOCKSTORE.GETBLOCK: EMPTY FILE")
@@ -26,18 +34,27 @@ func (bs *BlockStore) GetBlock(ctx context.Context, blockHash *BlockHash) (*Bloc
 		return emptyBlock, nil
 
 	}
-
-	if !ok {
+	val, err := bs.BlockStorage.Get(blockHash.Hash)
+	if err == ERR_BLOCK_NOT_FOUND {
 		// fmt.Println("BLOCKSTORE.GETBLOCK: Block not found")
-		return nil, fmt.Errorf("Block not found")
+		return nil, status.Errorf(codes.NotFound, "Block %s not found", blockHash.Hash)
+	}
+	if err != nil {
+		return nil, err
 	}
 	// fmt.Println("BLOCKSTORE.GETBLOCK: Block found")
+	if GetBlockHashString(val.BlockData) != blockHash.Hash {
+		return nil, status.Errorf(codes.DataLoss, "Stored block %s is corrupt", blockHash.Hash)
+	}
 	return val, nil
 }
 
 func (bs *BlockStore) PutBlock(ctx context.Context, block *Block) (*Success, error) { //MY CODE
 	// fmt.Println("BLOCKSTORE.PUTBLOCK: Adding block")
 	// fmt.Println("BLOCKSTORE.PUTBLOCK: Block data: ", block.BlockData)
+	if int(block.BlockSize) != len(block.BlockData) {
+		return &Success{Flag: false}, status.Errorf(codes.InvalidArgument, "Block size %d does not match its %d bytes of data", block.BlockSize, len(block.BlockData))
+	}
 	var hashString string
 	blockData := block.BlockData
 	if blockData == nil {
@@ -48,25 +65,35 @@ func (bs *BlockStore) PutBlock(ctx context.Context, block *Block) (*Success, err
 	}
 
 	// fmt.Println("BLOCKSTORE.PUTBLOCK: Hash string: ", hashString)
-	bs.BlockMap[hashString] = block
+	if err := bs.BlockStorage.Put(hashString, block); err != nil {
+		return &Success{Flag: false}, err
+	}
 	return &Success{Flag: true}, nil
 }
 
 // Given a list of hashes “in”, returns a list containing the
-// hashes that are not stored in the key-value store
+// hashes that are not stored in the key-value store. Blocks that are
+// stored are touched, the client is about to rely on them and they must
+// not be garbage collected before its UpdateFile lands.
 func (bs *BlockStore) MissingBlocks(ctx context.Context, blockHashesIn *BlockHashes) (*BlockHashes, error) { //MY CODE
 	// fmt.Println("BLOCKSTORE.MISSINGBLOCKS: Checking for missing blocks")
-	missingBlocks := &BlockHashes{}
-	for key, _ := range bs.BlockMap {
-		found := false
-		for _, hash := range blockHashesIn.Hashes {
-			if key == hash {
-				found = true
-				break
-			}
+	bs.gcMtx.RLock()
+	defer bs.gcMtx.RUnlock()
+	missingBlocks := &BlockHashes{Hashes: []string{}}
+	seen := make(map[string]bool)
+	for _, hash := range blockHashesIn.Hashes {
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		found, err := bs.BlockStorage.Has(hash)
+		if err != nil {
+			return nil, err
 		}
 		if !found {
-			missingBlocks.Hashes = append(missingBlocks.Hashes, key)
+			missingBlocks.Hashes = append(missingBlocks.Hashes, hash)
+		} else if err := bs.BlockStorage.Touch(hash); err != nil {
+			return nil, err
 		}
 	}
 	// fmt.Println("BLOCKSTORE.MISSINGBLOCKS: Missing blocks found")
@@ -75,21 +102,87 @@ func (bs *BlockStore) MissingBlocks(ctx context.Context, blockHashesIn *BlockHas
 
 // Return a list containing all blockHashes on this block server
 func (bs *BlockStore) GetBlockHashes(ctx context.Context, _ *emptypb.Empty) (*BlockHashes, error) {
-	for key, _ := range bs.BlockMap {
-		fmt.Println("BLOCKSTORE.GETBLOCKHASHES: ", key)
+	storedHashes, err := bs.BlockStorage.List()
+	if err != nil {
+		return nil, err
 	}
 	blockHashes := &BlockHashes{}
-	for key, _ := range bs.BlockMap {
-		blockHashes.Hashes = append(blockHashes.Hashes, key)
-	}
+	blockHashes.Hashes = append(blockHashes.Hashes, storedHashes...)
 	return blockHashes, nil
 }
 
+// Store every block sent over the stream. Each block is stored as it
+// arrives, so the blocks received before the stream breaks off are kept.
+func (bs *BlockStore) PutBlocks(stream BlockStore_PutBlocksServer) error {
+	for {
+		block, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&Success{Flag: true})
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := bs.PutBlock(stream.Context(), block); err != nil {
+			return err
+		}
+	}
+}
+
+// Stream back the blocks for the given hashes, in the order requested. The
+// stream ends with NotFound at the first hash that is not stored.
+func (bs *BlockStore) GetBlocks(blockHashesIn *BlockHashes, stream BlockStore_GetBlocksServer) error {
+	for _, hash := range blockHashesIn.Hashes {
+		block, err := bs.GetBlock(stream.Context(), &BlockHash{Hash: hash})
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteBlocks deletes the requested blocks, except for the ones stored or
+// touched within the grace period which may belong to a sync that has not
+// called UpdateFile yet. Returns the hashes that were deleted.
+func (bs *BlockStore) DeleteBlocks(ctx context.Context, request *DeleteBlocksRequest) (*BlockHashes, error) {
+	bs.gcMtx.Lock()
+	defer bs.gcMtx.Unlock()
+	gracePeriod := time.Duration(request.GracePeriodMillis) * time.Millisecond
+	deleted := &BlockHashes{Hashes: []string{}}
+	for _, hash := range request.Hashes {
+		if hash == EMPTYFILE_HASHVALUE || hash == TOMBSTONE_HASHVALUE {
+			continue
+		}
+		blockInfo, err := bs.BlockStorage.Stat(hash)
+		if err == ERR_BLOCK_NOT_FOUND {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if time.Since(blockInfo.StoredAt) < gracePeriod {
+			continue
+		}
+		if err := bs.BlockStorage.Delete(hash); err != nil {
+			return nil, err
+		}
+		deleted.Hashes = append(deleted.Hashes, hash)
+	}
+	return deleted, nil
+}
+
 // This line guarantees all method for BlockStore are implemented
 var _ BlockStoreInterface = new(BlockStore)
 
 func NewBlockStore() *BlockStore {
+	return NewBlockStoreWithStorage(NewMemoryBlockStorage())
+}
+
+// Create a BlockStore that keeps its blocks in storage
+func NewBlockStoreWithStorage(storage BlockStorage) *BlockStore {
 	return &BlockStore{
-		BlockMap: map[string]*Block{},
+		BlockStorage: storage,
 	}
 }
@@ -0,0 +1,247 @@
+package surfstore
+
+import (
+	context "context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startBlockStore serves a BlockStore keeping its blocks in blockDir, the
+// way SurfstoreServerExec does for -b
+func startBlockStore(t *testing.T, blockDir string) string {
+	t.Helper()
+	storage, err := NewFileBlockStorage(blockDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterBlockStoreServer(server, NewBlockStoreWithStorage(storage))
+	})
+}
+
+// Blocks put before a restart are served, listed and reported present by a
+// BlockStore started over the same directory
+func TestBlockStoreSurvivesRestart(t *testing.T) {
+	blockDir := t.TempDir()
+	client := NewSurfstoreRPCClient("", "", 0)
+	defer client.Close()
+	ctx := context.Background()
+
+	blocks := []*Block{}
+	hashes := []string{}
+	for _, data := range []string{"first block", "second block", "third block"} {
+		blocks = append(blocks, &Block{BlockData: []byte(data), BlockSize: int32(len(data))})
+		hashes = append(hashes, GetBlockHashString([]byte(data)))
+	}
+	var succ bool
+	if err := client.PutBlocks(ctx, blocks, startBlockStore(t, blockDir), &succ); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := startBlockStore(t, blockDir)
+	for i, hash := range hashes {
+		var block Block
+		if err := client.GetBlock(ctx, hash, restarted, &block); err != nil {
+			t.Fatalf("block %d after a restart: %v", i, err)
+		}
+		if string(block.BlockData) != string(blocks[i].BlockData) {
+			t.Fatalf("block %d is %q after a restart, want %q", i, block.BlockData, blocks[i].BlockData)
+		}
+	}
+	var missing []string
+	if err := client.MissingBlocks(ctx, hashes, restarted, &missing); err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Fatalf("%v missing after a restart", missing)
+	}
+	var stored []string
+	if err := client.GetBlockHashes(ctx, restarted, &stored); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(stored)
+	sort.Strings(hashes)
+	if len(stored) != len(hashes) {
+		t.Fatalf("%d blocks listed after a restart, want %d", len(stored), len(hashes))
+	}
+	for i := range hashes {
+		if stored[i] != hashes[i] {
+			t.Fatalf("listed %v after a restart, want %v", stored, hashes)
+		}
+	}
+}
+
+// A block whose size does not match its data is rejected with
+// InvalidArgument, alone or in a stream, and is not stored
+func TestPutBlockSizeMismatch(t *testing.T) {
+	addr := startBlockStore(t, t.TempDir())
+	client := NewSurfstoreRPCClient("", "", 0)
+	defer client.Close()
+	ctx := context.Background()
+
+	hash, block := testBlock("mismatched block")
+	block.BlockSize++
+	var succ bool
+	if err := client.PutBlock(ctx, block, addr, &succ); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("PutBlock got %v, want InvalidArgument", err)
+	}
+	if err := client.PutBlocks(ctx, []*Block{block}, addr, &succ); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("PutBlocks got %v, want InvalidArgument", err)
+	}
+	var missing []string
+	if err := client.MissingBlocks(ctx, []string{hash}, addr, &missing); err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 1 || missing[0] != hash {
+		t.Fatalf("%v reported missing, want the rejected block %s", missing, hash)
+	}
+}
+
+// A block sent twice in one stream is stored once, and a block asked for
+// twice is streamed back twice, in the order asked for
+func TestBlockStreamsDuplicateHashes(t *testing.T) {
+	addr := startBlockStore(t, t.TempDir())
+	client := NewSurfstoreRPCClient("", "", 0)
+	defer client.Close()
+	ctx := context.Background()
+
+	a, blockA := testBlock("block a")
+	b, blockB := testBlock("block b")
+	var succ bool
+	if err := client.PutBlocks(ctx, []*Block{blockA, blockA, blockB}, addr, &succ); err != nil || !succ {
+		t.Fatalf("PutBlocks got %v, %v", succ, err)
+	}
+	var stored []string
+	if err := client.GetBlockHashes(ctx, addr, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("%v stored, want each block once", stored)
+	}
+	var blocks []*Block
+	if err := client.GetBlocks(ctx, []string{a, b, a}, addr, &blocks); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"block a", "block b", "block a"} {
+		if string(blocks[i].BlockData) != want {
+			t.Fatalf("block %d is %q, want %q", i, blocks[i].BlockData, want)
+		}
+	}
+}
+
+// Asking for a block that is not stored ends the stream with NotFound
+func TestGetBlocksMissingHash(t *testing.T) {
+	addr := startBlockStore(t, t.TempDir())
+	client := NewSurfstoreRPCClient("", "", 0)
+	defer client.Close()
+	ctx := context.Background()
+
+	stored := putTestBlocks(t, client, addr, "stored block")
+	missing, _ := testBlock("missing block")
+	var blocks []*Block
+	err := client.GetBlocks(ctx, []string{stored[0], missing}, addr, &blocks)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("got %v, want NotFound", err)
+	}
+}
+
+// doneBlockStore reports when a PutBlocks stream has been handled
+type doneBlockStore struct {
+	*BlockStore
+	done chan error
+}
+
+func (bs *doneBlockStore) PutBlocks(stream BlockStore_PutBlocksServer) error {
+	err := bs.BlockStore.PutBlocks(stream)
+	bs.done <- err
+	return err
+}
+
+// The blocks received before a client cancels its PutBlocks stream stay
+// stored, whole, and no temp file is left behind
+func TestPutBlocksCancelled(t *testing.T) {
+	blockDir := t.TempDir()
+	storage, err := NewFileBlockStorage(blockDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockStore := &doneBlockStore{NewBlockStoreWithStorage(storage), make(chan error, 1)}
+	addr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterBlockStoreServer(server, blockStore)
+	})
+	client := NewSurfstoreRPCClient("", "", 0)
+	defer client.Close()
+	conn, err := client.getConn(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := NewBlockStoreClient(conn).PutBlocks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := []string{}
+	for _, data := range []string{strings.Repeat("a", 1<<20), strings.Repeat("b", 1<<20)} {
+		hash, block := testBlock(data)
+		if err := stream.Send(block); err != nil {
+			t.Fatal(err)
+		}
+		sent = append(sent, hash)
+	}
+	waitFor(t, "the sent blocks are stored", func() bool {
+		for _, hash := range sent {
+			if found, _ := storage.Has(hash); !found {
+				return false
+			}
+		}
+		return true
+	})
+	// may or may not arrive before the cancel, it is checked with the rest
+	// of the stored blocks below
+	_, inFlight := testBlock(strings.Repeat("c", 1<<20))
+	stream.Send(inFlight)
+	cancel()
+	if err := <-blockStore.done; status.Code(err) != codes.Canceled {
+		t.Fatalf("the stream ended with %v, want Canceled", err)
+	}
+
+	for _, hash := range sent {
+		block, err := storage.Get(hash)
+		if err != nil {
+			t.Fatalf("block %s sent before the cancel: %v", hash, err)
+		}
+		if GetBlockHashString(block.BlockData) != hash {
+			t.Fatalf("block %s is half written", hash)
+		}
+	}
+	err = filepath.WalkDir(blockDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !validBlockHash(d.Name()) {
+			t.Fatalf("%s left in the block directory", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes, err := storage.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range hashes {
+		if block, err := storage.Get(hash); err != nil || GetBlockHashString(block.BlockData) != hash {
+			t.Fatalf("block %s is half written (%v)", hash, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package surfstore
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"strconv"
+)
+
+// Chunker decides where a file is cut into blocks
+type Chunker interface {
+	// Read r to the end and call emit with each block, in order
+	Chunk(r io.Reader, emit func(blockData []byte)) error
+}
+
+// FixedSizeChunker cuts files into blocks of BlockSize bytes, the last
+// block holds whatever is left over
+type FixedSizeChunker struct {
+	BlockSize int
+}
+
+func (c *FixedSizeChunker) Chunk(r io.Reader, emit func(blockData []byte)) error {
+	if c.BlockSize <= 0 || c.BlockSize > MAX_BLOCK_SIZE {
+		return fmt.Errorf("Block size %d is not between 1 and %d", c.BlockSize, MAX_BLOCK_SIZE)
+	}
+	for {
+		blockData := make([]byte, c.BlockSize)
+		n, err := io.ReadFull(r, blockData)
+		if n > 0 {
+			emit(blockData[:n])
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ContentDefinedChunker cuts files where a rolling gear hash of the content
+// matches a mask (FastCDC with normalized chunking). Block boundaries
+// follow the content, so inserting or removing bytes only changes the
+// blocks around the edit instead of every block after it.
+//
+// Blocks are between MinSize and MaxSize bytes and AvgSize on average.
+// They are ordinary blocks to the rest of Surfstore, only their hashes end
+// up in FileMetaData and index.db.
+type ContentDefinedChunker struct {
+	MinSize int
+	AvgSize int
+	MaxSize int
+
+	// stricter mask used before AvgSize, looser one after it
+	maskSmall uint64
+	maskLarge uint64
+}
+
+// gearTable maps each byte to a pseudo random value. It is derived from
+// sha256 so every client cuts the same content at the same places; changing
+// it would change every content-defined block hash.
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+	for i := range table {
+		sum := sha256.Sum256([]byte("surfstore-gear-" + strconv.Itoa(i)))
+		table[i] = binary.BigEndian.Uint64(sum[:8])
+	}
+	return table
+}()
+
+func (c *ContentDefinedChunker) Chunk(r io.Reader, emit func(blockData []byte)) error {
+	buf := make([]byte, c.MaxSize)
+	n := 0
+	eof := false
+	for {
+		if !eof {
+			m, err := io.ReadFull(r, buf[n:])
+			n += m
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				eof = true
+			} else if err != nil {
+				return err
+			}
+		}
+		if n == 0 {
+			return nil
+		}
+		cut := c.cutPoint(buf[:n])
+		blockData := make([]byte, cut)
+		copy(blockData, buf[:cut])
+		emit(blockData)
+		copy(buf, buf[cut:n])
+		n -= cut
+	}
+}
+
+// cutPoint returns the length of the block at the start of data
+func (c *ContentDefinedChunker) cutPoint(data []byte) int {
+	n := len(data)
+	if n <= c.MinSize {
+		return n
+	}
+	if n > c.MaxSize {
+		n = c.MaxSize
+	}
+	normal := c.AvgSize
+	if n < normal {
+		normal = n
+	}
+	var fingerprint uint64
+	i := c.MinSize
+	for ; i < normal; i++ {
+		fingerprint = (fingerprint << 1) + gearTable[data[i]]
+		if fingerprint&c.maskSmall == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fingerprint = (fingerprint << 1) + gearTable[data[i]]
+		if fingerprint&c.maskLarge == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// highBitsMask returns a mask of the top n bits, which depend on the last
+// 64 bytes hashed
+func highBitsMask(n int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	return ^uint64(0) << (64 - n)
+}
+
+// This line guarantees all method for the chunkers are implemented
+var _ Chunker = new(FixedSizeChunker)
+var _ Chunker = new(ContentDefinedChunker)
+
+// Create a content-defined chunker,
+// 0 < minSize <= avgSize <= maxSize <= MAX_BLOCK_SIZE
+func NewContentDefinedChunker(minSize int, avgSize int, maxSize int) (*ContentDefinedChunker, error) {
+	if minSize <= 0 || minSize > avgSize || avgSize > maxSize {
+		return nil, fmt.Errorf("Invalid chunk sizes min=%d avg=%d max=%d", minSize, avgSize, maxSize)
+	}
+	if maxSize > MAX_BLOCK_SIZE {
+		return nil, fmt.Errorf("Largest chunk size %d is above the block size limit of %d", maxSize, MAX_BLOCK_SIZE)
+	}
+	avgBits := bits.Len(uint(avgSize)) - 1
+	return &ContentDefinedChunker{
+		MinSize:   minSize,
+		AvgSize:   avgSize,
+		MaxSize:   maxSize,
+		maskSmall: highBitsMask(avgBits + 1),
+		maskLarge: highBitsMask(avgBits - 1),
+	}, nil
+}
@@ -0,0 +1,113 @@
+package surfstore
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func newTestChunker(t *testing.T) *ContentDefinedChunker {
+	t.Helper()
+	chunker, err := NewContentDefinedChunker(1024, 4096, 16384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chunker
+}
+
+func chunkBytes(t *testing.T, chunker Chunker, data []byte) [][]byte {
+	t.Helper()
+	chunks := [][]byte{}
+	if err := chunker.Chunk(bytes.NewReader(data), func(blockData []byte) {
+		chunks = append(chunks, blockData)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return chunks
+}
+
+func randomBytes(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+// Every block but the last is between MinSize and MaxSize, and the blocks
+// put back together are the file
+func TestContentDefinedChunkSizes(t *testing.T) {
+	chunker := newTestChunker(t)
+	data := randomBytes(1 << 20)
+	// a long run of one byte never matches the mask, cuts fall at MaxSize
+	data = append(data, make([]byte, 100000)...)
+	chunks := chunkBytes(t, chunker, data)
+	for i, chunk := range chunks[:len(chunks)-1] {
+		if len(chunk) < chunker.MinSize || len(chunk) > chunker.MaxSize {
+			t.Fatalf("block %d is %d bytes, want %d to %d", i, len(chunk), chunker.MinSize, chunker.MaxSize)
+		}
+	}
+	if last := chunks[len(chunks)-1]; len(last) == 0 || len(last) > chunker.MaxSize {
+		t.Fatalf("last block is %d bytes", len(last))
+	}
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("blocks do not add up to the file")
+	}
+}
+
+// The same content is always cut at the same places
+func TestContentDefinedChunkDeterministic(t *testing.T) {
+	data := randomBytes(1 << 20)
+	first := chunkBytes(t, newTestChunker(t), data)
+	second := chunkBytes(t, newTestChunker(t), data)
+	if len(first) != len(second) {
+		t.Fatalf("cut into %d and then %d blocks", len(first), len(second))
+	}
+	for i := range first {
+		if !bytes.Equal(first[i], second[i]) {
+			t.Fatalf("block %d differs between two runs", i)
+		}
+	}
+}
+
+// Inserting a byte near the start of a large file only changes the blocks
+// around it
+func TestContentDefinedChunkInsert(t *testing.T) {
+	chunker := newTestChunker(t)
+	data := randomBytes(1 << 20)
+	before := map[string]bool{}
+	for _, chunk := range chunkBytes(t, chunker, data) {
+		before[GetBlockHashString(chunk)] = true
+	}
+
+	edited := append(append(append([]byte{}, data[:100]...), 'x'), data[100:]...)
+	after := chunkBytes(t, chunker, edited)
+	changed := 0
+	for _, chunk := range after {
+		if !before[GetBlockHashString(chunk)] {
+			changed++
+		}
+	}
+	if changed > 2 {
+		t.Fatalf("%d of %d blocks changed after inserting one byte", changed, len(after))
+	}
+}
+
+// A block size that cannot be cut or stored fails instead of looping
+func TestFixedSizeChunkerLimits(t *testing.T) {
+	for _, blockSize := range []int{0, -1, MAX_BLOCK_SIZE + 1} {
+		chunker := &FixedSizeChunker{BlockSize: blockSize}
+		if err := chunker.Chunk(bytes.NewReader([]byte("data")), func([]byte) {}); err == nil {
+			t.Fatalf("block size %d accepted", blockSize)
+		}
+	}
+}
+
+func TestNewContentDefinedChunkerLimits(t *testing.T) {
+	for _, sizes := range [][3]int{{0, 4096, 16384}, {8192, 4096, 16384}, {1024, 32768, 16384}, {1024, 4096, MAX_BLOCK_SIZE + 1}} {
+		if _, err := NewContentDefinedChunker(sizes[0], sizes[1], sizes[2]); err == nil {
+			t.Fatalf("chunk sizes %v accepted", sizes)
+		}
+	}
+	if _, err := NewContentDefinedChunker(1024, 4096, MAX_BLOCK_SIZE); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,234 @@
+package surfstore
+
+import (
+	context "context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	grpc "google.golang.org/grpc"
+)
+
+// Stress tests for many clients hitting the servers at once, meant to be
+// run with go test -race
+
+const stressClients int = 16
+const stressRounds int = 20
+
+// startTestServer serves whatever register puts on a gRPC server on a free
+// local port and returns its address
+func startTestServer(t *testing.T, register func(server *grpc.Server, addr string)) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	register(server, l.Addr().String())
+	go server.Serve(l)
+	t.Cleanup(server.Stop)
+	return l.Addr().String()
+}
+
+// Every client keeps trying to bump the same file, each version must be
+// won by exactly one of them
+func TestConcurrentUpdateFile(t *testing.T) {
+	metaStore, err := NewMetaStore(nil, t.TempDir(), DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterMetaStoreServer(server, metaStore)
+	})
+
+	var mtx sync.Mutex
+	winners := map[int32]int{}
+	var wg sync.WaitGroup
+	errs := make(chan error, stressClients)
+	for c := 0; c < stressClients; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			client := NewSurfstoreRPCClient(addr, "", 0)
+			defer client.Close()
+			ctx := context.Background()
+			ownFile := "client" + strconv.Itoa(c) + ".txt"
+			for round := 1; round <= stressRounds; round++ {
+				var fileInfoMap map[string]*FileMetaData
+				if err := client.GetFileInfoMap(ctx, &fileInfoMap); err != nil {
+					errs <- err
+					return
+				}
+				var version int32
+				if shared, ok := fileInfoMap["shared.txt"]; ok {
+					version = shared.Version
+				}
+				update := &FileMetaData{Filename: "shared.txt", Version: version + 1, BlockHashList: []string{strconv.Itoa(c)}}
+				var latestVersion int32
+				if err := client.UpdateFile(ctx, update, &latestVersion); err == nil {
+					mtx.Lock()
+					winners[latestVersion]++
+					mtx.Unlock()
+				}
+
+				// nobody else touches this file, every update must land
+				own := &FileMetaData{Filename: ownFile, Version: int32(round), BlockHashList: []string{strconv.Itoa(round)}}
+				if err := client.UpdateFile(ctx, own, &latestVersion); err != nil {
+					errs <- fmt.Errorf("%s version %d: %v", ownFile, round, err)
+					return
+				}
+
+				var changes FileInfoChanges
+				if err := client.GetChangesSince(ctx, 0, &changes); err != nil {
+					errs <- err
+					return
+				}
+				var referenced []string
+				if err := client.GetReferencedBlockHashes(ctx, &referenced); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	final := metaStore.FileMetaMap["shared.txt"].Version
+	if int(final) != len(winners) {
+		t.Fatalf("shared.txt is at version %d after %d successful updates", final, len(winners))
+	}
+	for version := int32(1); version <= final; version++ {
+		if winners[version] != 1 {
+			t.Fatalf("version %d was won %d times", version, winners[version])
+		}
+	}
+	for c := 0; c < stressClients; c++ {
+		ownFile := "client" + strconv.Itoa(c) + ".txt"
+		if got := metaStore.FileMetaMap[ownFile].Version; got != int32(stressRounds) {
+			t.Fatalf("%s is at version %d, want %d", ownFile, got, stressRounds)
+		}
+	}
+}
+
+// Clients upload, check and download their own blocks while a shared set
+// of blocks is uploaded and garbage collected under them
+func TestConcurrentBlockStore(t *testing.T) {
+	storages := map[string]func(blockDir string) (BlockStorage, error){
+		"memory": func(string) (BlockStorage, error) { return NewMemoryBlockStorage(), nil },
+		"fs":     func(blockDir string) (BlockStorage, error) { return NewFileBlockStorage(blockDir) },
+		"sqlite": func(blockDir string) (BlockStorage, error) { return NewSQLiteBlockStorage(blockDir) },
+	}
+	for name, newStorage := range storages {
+		t.Run(name, func(t *testing.T) {
+			storage, err := newStorage(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr := startTestServer(t, func(server *grpc.Server, _ string) {
+				RegisterBlockStoreServer(server, NewBlockStoreWithStorage(storage))
+			})
+			stressBlockStore(t, addr)
+		})
+	}
+}
+
+func stressBlockStore(t *testing.T, addr string) {
+	sharedBlocks := []*Block{}
+	sharedHashes := []string{}
+	for i := 0; i < 8; i++ {
+		data := []byte("shared block " + strconv.Itoa(i))
+		sharedBlocks = append(sharedBlocks, &Block{BlockData: data, BlockSize: int32(len(data))})
+		sharedHashes = append(sharedHashes, GetBlockHashString(data))
+	}
+
+	// the garbage collector
+	done := make(chan struct{})
+	gcErr := make(chan error, 1)
+	go func() {
+		client := NewSurfstoreRPCClient("", "", 0)
+		defer client.Close()
+		ctx := context.Background()
+		for {
+			select {
+			case <-done:
+				gcErr <- nil
+				return
+			default:
+			}
+			var deleted []string
+			if err := client.DeleteBlocks(ctx, sharedHashes, 0, addr, &deleted); err != nil {
+				gcErr <- err
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, stressClients)
+
+	for c := 0; c < stressClients; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			client := NewSurfstoreRPCClient("", "", 0)
+			defer client.Close()
+			ctx := context.Background()
+			for round := 0; round < stressRounds; round++ {
+				data := []byte(fmt.Sprintf("client %d round %d", c, round))
+				hash := GetBlockHashString(data)
+				blocks := append([]*Block{{BlockData: data, BlockSize: int32(len(data))}}, sharedBlocks...)
+				var succ bool
+				if err := client.PutBlocks(ctx, blocks, addr, &succ); err != nil {
+					errs <- err
+					return
+				}
+				var missing []string
+				if err := client.MissingBlocks(ctx, append([]string{hash}, sharedHashes...), addr, &missing); err != nil {
+					errs <- err
+					return
+				}
+				if contains(missing, hash) {
+					errs <- fmt.Errorf("block %s missing right after it was put", hash)
+					return
+				}
+				var got []*Block
+				if err := client.GetBlocks(ctx, []string{hash}, addr, &got); err != nil {
+					errs <- err
+					return
+				}
+				if len(got) != 1 || string(got[0].BlockData) != string(data) {
+					errs <- fmt.Errorf("block %s read back wrong", hash)
+					return
+				}
+				// the shared blocks may be gone, but never corrupt
+				for i, sharedHash := range sharedHashes {
+					var block Block
+					if err := client.GetBlock(ctx, sharedHash, addr, &block); err == nil && string(block.BlockData) != string(sharedBlocks[i].BlockData) {
+						errs <- fmt.Errorf("shared block %s read back wrong", sharedHash)
+						return
+					}
+				}
+				var stored []string
+				if err := client.GetBlockHashes(ctx, addr, &stored); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+	close(done)
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if err := <-gcErr; err != nil {
+		t.Fatal(err)
+	}
+}
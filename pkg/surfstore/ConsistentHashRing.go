@@ -3,30 +3,84 @@ package surfstore
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"fmt"
 	"sort"
+	"strconv"
 )
 
+// ConsistentHashRing maps blocks to servers. A ring must not be changed
+// once it is in use, which makes it safe for concurrent readers; a change
+// of servers builds a new ring instead.
 type ConsistentHashRing struct {
 	ServerMap map[string]string
+	// points on the ring each server gets per unit of weight
+	VirtualNodes int
+	// relative capacity of each server, servers missing here have weight 1
+	Weights map[string]int
+	// number of distinct servers each block is stored on, at least 1
+	ReplicationFactor int
+	// the keys of ServerMap in ascending order, for binary search
+	sortedHashes []string
 }
 
 func (c ConsistentHashRing) GetResponsibleServer(blockId string) string {
+	hashes := c.hashes()
+	if len(hashes) == 0 {
+		return ""
+	}
+	return c.ServerMap[hashes[successor(hashes, blockId)]]
+}
 
-	hashes := []string{}
-	for h := range c.ServerMap {
-		hashes = append(hashes, h)
+// GetResponsibleServers returns the servers a block is replicated on: the
+// first ReplicationFactor distinct servers clockwise from blockId, the
+// server returned by GetResponsibleServer first. There are fewer if the ring
+// has fewer servers.
+func (c ConsistentHashRing) GetResponsibleServers(blockId string) []string {
+	replicationFactor := c.ReplicationFactor
+	if replicationFactor < 1 {
+		replicationFactor = 1
 	}
-	sort.Strings(hashes)
-	fmt.Println("BlockId: ", blockId)
-	for i := 0; i < len(hashes); i++ {
-		if hashes[i] > blockId {
-			fmt.Println("ServerMap: ", c.ServerMap[hashes[i]])
-			return c.ServerMap[hashes[i]]
+	hashes := c.hashes()
+	if len(hashes) == 0 {
+		return []string{}
+	}
+	start := successor(hashes, blockId)
+	servers := make([]string, 0, replicationFactor)
+	for i := 0; i < len(hashes) && len(servers) < replicationFactor; i++ {
+		serverAddr := c.ServerMap[hashes[(start+i)%len(hashes)]]
+		if !contains(servers, serverAddr) {
+			servers = append(servers, serverAddr)
 		}
 	}
-	fmt.Println("ServerMap: ", c.ServerMap[hashes[0]])
-	return c.ServerMap[hashes[0]]
+	return servers
+}
+
+// successor returns the index in hashes of the first point clockwise from
+// blockId
+func successor(hashes []string, blockId string) int {
+	i := sort.Search(len(hashes), func(i int) bool { return hashes[i] > blockId })
+	if i == len(hashes) {
+		// wrap around
+		return 0
+	}
+	return i
+}
+
+// hashes returns the points on the ring in ascending order
+func (c ConsistentHashRing) hashes() []string {
+	if c.sortedHashes != nil {
+		return c.sortedHashes
+	}
+	// a ring that was not built by NewWeightedConsistentHashRing
+	return sortedKeys(c.ServerMap)
+}
+
+func sortedKeys(serverMap map[string]string) []string {
+	hashes := make([]string, 0, len(serverMap))
+	for h := range serverMap {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+	return hashes
 }
 
 func (c ConsistentHashRing) Hash(addr string) string {
@@ -35,13 +89,82 @@ func (c ConsistentHashRing) Hash(addr string) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
+// virtualNodeKey is what the i-th point of a server is hashed from. The
+// first point is where the server sat before virtual nodes, so a ring with
+// one virtual node and weight 1 maps blocks exactly as before.
+func virtualNodeKey(serverAddr string, i int) string {
+	if i == 0 {
+		return "blockstore" + serverAddr
+	}
+	return "blockstore" + serverAddr + "#" + strconv.Itoa(i)
+}
+
 func NewConsistentHashRing(serverAddrs []string) *ConsistentHashRing {
-	consistentRing := &ConsistentHashRing{ServerMap: make(map[string]string)}
-	serverMap := make(map[string]string)
-	for _, serverPort := range serverAddrs {
-		serverHash := consistentRing.Hash("blockstore" + serverPort)
-		consistentRing.ServerMap[serverHash] = serverPort
-	}
-	fmt.Println("ServerMap: ", serverMap)
-	return &ConsistentHashRing{ServerMap: consistentRing.ServerMap}
+	return NewWeightedConsistentHashRing(serverAddrs, nil, 1)
+}
+
+// Create a ring where each server owns virtualNodes times its weight points.
+// More points spread a server's share of the blocks more evenly, and a
+// server with twice the weight gets about twice the blocks.
+func NewWeightedConsistentHashRing(serverAddrs []string, weights map[string]int, virtualNodes int) *ConsistentHashRing {
+	if virtualNodes < 1 {
+		virtualNodes = 1
+	}
+	consistentRing := &ConsistentHashRing{
+		ServerMap:         make(map[string]string),
+		VirtualNodes:      virtualNodes,
+		Weights:           make(map[string]int),
+		ReplicationFactor: 1,
+	}
+	for _, serverAddr := range serverAddrs {
+		weight := 1
+		if w, ok := weights[serverAddr]; ok && w > 0 {
+			weight = w
+		}
+		consistentRing.Weights[serverAddr] = weight
+		for i := 0; i < virtualNodes*weight; i++ {
+			consistentRing.ServerMap[consistentRing.Hash(virtualNodeKey(serverAddr, i))] = serverAddr
+		}
+	}
+	consistentRing.sortedHashes = sortedKeys(consistentRing.ServerMap)
+	return consistentRing
+}
+
+// servers returns the address of every server on the ring
+func (c ConsistentHashRing) servers() []string {
+	serverAddrs := make([]string, 0, len(c.Weights))
+	for serverAddr := range c.Weights {
+		serverAddrs = append(serverAddrs, serverAddr)
+	}
+	sort.Strings(serverAddrs)
+	return serverAddrs
+}
+
+// membership describes the ring, listing its servers in the order of
+// serverAddrs
+func (c ConsistentHashRing) membership(serverAddrs []string) *BlockStoreMembership {
+	membership := &BlockStoreMembership{
+		Members:           make([]*BlockStoreMember, 0, len(serverAddrs)),
+		VirtualNodes:      int32(c.VirtualNodes),
+		ReplicationFactor: int32(c.ReplicationFactor),
+	}
+	for _, serverAddr := range serverAddrs {
+		membership.Members = append(membership.Members, &BlockStoreMember{Addr: serverAddr, Weight: int32(c.Weights[serverAddr])})
+	}
+	return membership
+}
+
+// newRingFromMembership creates the ring membership describes
+func newRingFromMembership(membership *BlockStoreMembership) *ConsistentHashRing {
+	serverAddrs := make([]string, 0, len(membership.Members))
+	weights := make(map[string]int, len(membership.Members))
+	for _, member := range membership.Members {
+		serverAddrs = append(serverAddrs, member.Addr)
+		weights[member.Addr] = int(member.Weight)
+	}
+	ring := NewWeightedConsistentHashRing(serverAddrs, weights, int(membership.VirtualNodes))
+	if membership.ReplicationFactor > 1 {
+		ring.ReplicationFactor = int(membership.ReplicationFactor)
+	}
+	return ring
 }
@@ -0,0 +1,199 @@
+package surfstore
+
+import (
+	context "context"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+const benchmarkBlockCount int = 100000
+
+func benchmarkRing() *ConsistentHashRing {
+	serverAddrs := []string{}
+	for i := 0; i < 8; i++ {
+		serverAddrs = append(serverAddrs, "localhost:"+strconv.Itoa(8081+i))
+	}
+	return NewWeightedConsistentHashRing(serverAddrs, nil, 16)
+}
+
+func benchmarkBlockHashes() []string {
+	blockHashes := make([]string, benchmarkBlockCount)
+	for i := range blockHashes {
+		blockHashes[i] = GetBlockHashString([]byte(strconv.Itoa(i)))
+	}
+	return blockHashes
+}
+
+// unsortedResponsibleServer is how a lookup worked before the ring kept its
+// points sorted: collect and sort them on every call
+func unsortedResponsibleServer(c *ConsistentHashRing, blockId string) string {
+	hashes := []string{}
+	for h := range c.ServerMap {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+	for i := 0; i < len(hashes); i++ {
+		if hashes[i] > blockId {
+			return c.ServerMap[hashes[i]]
+		}
+	}
+	return c.ServerMap[hashes[0]]
+}
+
+// The sorted ring must place every block where the old lookup did
+func TestGetResponsibleServerMatchesUnsorted(t *testing.T) {
+	ring := benchmarkRing()
+	for _, blockHash := range benchmarkBlockHashes()[:1000] {
+		if got, want := ring.GetResponsibleServer(blockHash), unsortedResponsibleServer(ring, blockHash); got != want {
+			t.Fatalf("block %s: got %s, want %s", blockHash, got, want)
+		}
+	}
+}
+
+// blockShares counts the blocks each server of ring is responsible for
+func blockShares(ring *ConsistentHashRing, blockHashes []string) map[string]int {
+	shares := map[string]int{}
+	for _, blockHash := range blockHashes {
+		shares[ring.GetResponsibleServer(blockHash)]++
+	}
+	return shares
+}
+
+// skew is the largest share of the blocks relative to an even split
+func skew(shares map[string]int, serverCount int, blockCount int) float64 {
+	largest := 0
+	for _, share := range shares {
+		if share > largest {
+			largest = share
+		}
+	}
+	return float64(largest) * float64(serverCount) / float64(blockCount)
+}
+
+// More virtual nodes spread the blocks more evenly
+func TestVirtualNodesReduceSkew(t *testing.T) {
+	serverAddrs := benchmarkRing().servers()
+	blockHashes := benchmarkBlockHashes()
+	single := skew(blockShares(NewWeightedConsistentHashRing(serverAddrs, nil, 1), blockHashes), len(serverAddrs), len(blockHashes))
+	many := skew(blockShares(NewWeightedConsistentHashRing(serverAddrs, nil, 64), blockHashes), len(serverAddrs), len(blockHashes))
+	if many >= single {
+		t.Fatalf("64 virtual nodes skew %.2f, no better than %.2f with one", many, single)
+	}
+	if many > 1.25 {
+		t.Fatalf("the busiest server gets %.2f times its share with 64 virtual nodes", many)
+	}
+}
+
+// A server with weight 2 gets about twice the blocks of the others
+func TestWeightedServerShare(t *testing.T) {
+	serverAddrs := benchmarkRing().servers()
+	heavy := serverAddrs[0]
+	blockHashes := benchmarkBlockHashes()
+	shares := blockShares(NewWeightedConsistentHashRing(serverAddrs, map[string]int{heavy: 2}, 64), blockHashes)
+	others := (len(blockHashes) - shares[heavy]) / (len(serverAddrs) - 1)
+	ratio := float64(shares[heavy]) / float64(others)
+	if ratio < 1.7 || ratio > 2.3 {
+		t.Fatalf("weight 2 gets %.2f times the blocks of weight 1", ratio)
+	}
+}
+
+// legacyResponsibleServer places a block the way the ring did before
+// virtual nodes and weights, one point per server
+func legacyResponsibleServer(serverAddrs []string, blockId string) string {
+	ring := ConsistentHashRing{ServerMap: map[string]string{}}
+	for _, serverAddr := range serverAddrs {
+		ring.ServerMap[ring.Hash("blockstore"+serverAddr)] = serverAddr
+	}
+	return unsortedResponsibleServer(&ring, blockId)
+}
+
+// One virtual node of weight 1 keeps every block where it was before
+// virtual nodes, so existing BlockStores keep serving their blocks
+func TestSingleVirtualNodeKeepsPlacement(t *testing.T) {
+	serverAddrs := benchmarkRing().servers()
+	weights := map[string]int{}
+	for _, serverAddr := range serverAddrs {
+		weights[serverAddr] = 1
+	}
+	rings := map[string]*ConsistentHashRing{
+		"NewConsistentHashRing":                 NewConsistentHashRing(serverAddrs),
+		"NewWeightedConsistentHashRing":         NewWeightedConsistentHashRing(serverAddrs, nil, 1),
+		"NewWeightedConsistentHashRing weights": NewWeightedConsistentHashRing(serverAddrs, weights, 1),
+	}
+	for name, ring := range rings {
+		for _, blockHash := range benchmarkBlockHashes()[:1000] {
+			if got, want := ring.GetResponsibleServer(blockHash), legacyResponsibleServer(serverAddrs, blockHash); got != want {
+				t.Fatalf("%s: block %s is on %s, was on %s", name, blockHash, got, want)
+			}
+		}
+	}
+}
+
+// A block is replicated on distinct servers in ring order, all of them
+// when there are fewer servers than replicas
+func TestGetResponsibleServersDistinct(t *testing.T) {
+	serverAddrs := benchmarkRing().servers()[:4]
+	for _, replicationFactor := range []int{1, 3, 4, 6} {
+		ring := NewWeightedConsistentHashRing(serverAddrs, nil, 16)
+		ring.ReplicationFactor = replicationFactor
+		want := replicationFactor
+		if want > len(serverAddrs) {
+			want = len(serverAddrs)
+		}
+		for _, blockHash := range benchmarkBlockHashes()[:1000] {
+			replicas := ring.GetResponsibleServers(blockHash)
+			if len(replicas) != want {
+				t.Fatalf("replication factor %d: block %s is on %v, want %d servers", replicationFactor, blockHash, replicas, want)
+			}
+			if replicas[0] != ring.GetResponsibleServer(blockHash) {
+				t.Fatalf("replication factor %d: first replica %s is not the responsible server", replicationFactor, replicas[0])
+			}
+			seen := map[string]bool{}
+			for _, replica := range replicas {
+				if seen[replica] {
+					t.Fatalf("replication factor %d: block %s is on %v twice", replicationFactor, blockHash, replica)
+				}
+				seen[replica] = true
+			}
+		}
+	}
+}
+
+func BenchmarkGetResponsibleServer(b *testing.B) {
+	ring := benchmarkRing()
+	blockHashes := benchmarkBlockHashes()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, blockHash := range blockHashes {
+			ring.GetResponsibleServer(blockHash)
+		}
+	}
+}
+
+func BenchmarkGetResponsibleServerUnsorted(b *testing.B) {
+	ring := benchmarkRing()
+	blockHashes := benchmarkBlockHashes()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, blockHash := range blockHashes {
+			unsortedResponsibleServer(ring, blockHash)
+		}
+	}
+}
+
+func BenchmarkGetBlockStoreMap(b *testing.B) {
+	ring := benchmarkRing()
+	metaStore, err := NewMetaStore(ring.servers(), "", 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	metaStore.SetConsistentHashRing(ring)
+	request := &BlockHashes{Hashes: benchmarkBlockHashes()}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := metaStore.GetBlockStoreMap(context.Background(), request); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
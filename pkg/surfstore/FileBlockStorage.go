@@ -0,0 +1,151 @@
+package surfstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileBlockStorage persists every block as its own file under BlockDir.
+// Blocks are content addressed and fanned out into subdirectories by hash
+// prefix, the layout -b has always used, so blocks persisted before there
+// were storage backends are still served.
+type FileBlockStorage struct {
+	BlockDir string
+}
+
+func (s *FileBlockStorage) blockPath(hash string) string {
+	return filepath.Join(s.BlockDir, hash[:2], hash)
+}
+
+func (s *FileBlockStorage) Get(hash string) (*Block, error) {
+	if !validBlockHash(hash) {
+		return nil, ERR_BLOCK_NOT_FOUND
+	}
+	blockData, err := os.ReadFile(s.blockPath(hash))
+	if os.IsNotExist(err) {
+		return nil, ERR_BLOCK_NOT_FOUND
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Block{BlockData: blockData, BlockSize: int32(len(blockData))}, nil
+}
+
+func (s *FileBlockStorage) Put(hash string, block *Block) error {
+	if !validBlockHash(hash) {
+		return fmt.Errorf("Invalid block hash %q", hash)
+	}
+	ok, err := s.Has(hash)
+	if err != nil || ok {
+		// same hash means same content, nothing to write
+		return err
+	}
+	blockPath := s.blockPath(hash)
+	if err := os.MkdirAll(filepath.Dir(blockPath), 0755); err != nil {
+		return err
+	}
+	// written to a temp file and renamed into place so a crash never
+	// leaves a truncated block behind under its final name, or loses a
+	// block it was told is stored
+	return writeFileAtomic(blockPath, block.BlockData)
+}
+
+func (s *FileBlockStorage) Has(hash string) (bool, error) {
+	_, err := s.Stat(hash)
+	if err == ERR_BLOCK_NOT_FOUND {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *FileBlockStorage) List() ([]string, error) {
+	hashes := []string{}
+	prefixDirs, err := os.ReadDir(s.BlockDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, prefixDir := range prefixDirs {
+		if !prefixDir.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(s.BlockDir, prefixDir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if validBlockHash(entry.Name()) {
+				hashes = append(hashes, entry.Name())
+			}
+		}
+	}
+	return hashes, nil
+}
+
+func (s *FileBlockStorage) Delete(hash string) error {
+	if !validBlockHash(hash) {
+		return nil
+	}
+	err := os.Remove(s.blockPath(hash))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileBlockStorage) Stat(hash string) (*BlockInfo, error) {
+	if !validBlockHash(hash) {
+		return nil, ERR_BLOCK_NOT_FOUND
+	}
+	stat, err := os.Stat(s.blockPath(hash))
+	if os.IsNotExist(err) {
+		return nil, ERR_BLOCK_NOT_FOUND
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &BlockInfo{Hash: hash, Size: stat.Size(), StoredAt: stat.ModTime()}, nil
+}
+
+func (s *FileBlockStorage) Touch(hash string) error {
+	if !validBlockHash(hash) {
+		return nil
+	}
+	now := time.Now()
+	err := os.Chtimes(s.blockPath(hash), now, now)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// validBlockHash reports whether hash is a hex encoded sha256 hash (or the
+// empty file hash), so it is safe to use as a file name.
+func validBlockHash(hash string) bool {
+	if hash == EMPTYFILE_HASHVALUE {
+		return true
+	}
+	if len(hash) != 64 {
+		return false
+	}
+	for _, c := range hash {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// This line guarantees all method for FileBlockStorage are implemented
+var _ BlockStorage = new(FileBlockStorage)
+
+// Create a FileBlockStorage that keeps its blocks under blockDir
+func NewFileBlockStorage(blockDir string) (*FileBlockStorage, error) {
+	if err := os.MkdirAll(blockDir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileBlockStorage{
+		BlockDir: blockDir,
+	}, nil
+}
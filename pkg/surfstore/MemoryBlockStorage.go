@@ -0,0 +1,89 @@
+package surfstore
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryBlockStorage keeps blocks in a map, they are lost when the server
+// stops
+type MemoryBlockStorage struct {
+	mtx      sync.RWMutex
+	BlockMap map[string]*Block
+	storedAt map[string]time.Time
+}
+
+func (s *MemoryBlockStorage) Get(hash string) (*Block, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	val, ok := s.BlockMap[hash]
+	if !ok {
+		return nil, ERR_BLOCK_NOT_FOUND
+	}
+	return val, nil
+}
+
+func (s *MemoryBlockStorage) Put(hash string, block *Block) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.BlockMap[hash]; ok {
+		return nil
+	}
+	s.BlockMap[hash] = block
+	s.storedAt[hash] = time.Now()
+	return nil
+}
+
+func (s *MemoryBlockStorage) Has(hash string) (bool, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	_, ok := s.BlockMap[hash]
+	return ok, nil
+}
+
+func (s *MemoryBlockStorage) List() ([]string, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	hashes := []string{}
+	for key := range s.BlockMap {
+		hashes = append(hashes, key)
+	}
+	return hashes, nil
+}
+
+func (s *MemoryBlockStorage) Delete(hash string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.BlockMap, hash)
+	delete(s.storedAt, hash)
+	return nil
+}
+
+func (s *MemoryBlockStorage) Stat(hash string) (*BlockInfo, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	val, ok := s.BlockMap[hash]
+	if !ok {
+		return nil, ERR_BLOCK_NOT_FOUND
+	}
+	return &BlockInfo{Hash: hash, Size: int64(len(val.BlockData)), StoredAt: s.storedAt[hash]}, nil
+}
+
+func (s *MemoryBlockStorage) Touch(hash string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.BlockMap[hash]; ok {
+		s.storedAt[hash] = time.Now()
+	}
+	return nil
+}
+
+// This line guarantees all method for MemoryBlockStorage are implemented
+var _ BlockStorage = new(MemoryBlockStorage)
+
+func NewMemoryBlockStorage() *MemoryBlockStorage {
+	return &MemoryBlockStorage{
+		BlockMap: map[string]*Block{},
+		storedAt: map[string]time.Time{},
+	}
+}
@@ -3,36 +3,207 @@ package surfstore
 import (
 	context "context"
 	"fmt"
+	"log"
+	"sync"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 type MetaStore struct {
-	FileMetaMap        map[string]*FileMetaData
+	// mtx guards FileMetaMap, fileSequences and fileHistory. UpdateFile
+	// holds it from the version check until the update is applied.
+	mtx         sync.RWMutex
+	FileMetaMap map[string]*FileMetaData
+	// ringMtx guards BlockStoreAddrs, ConsistentHashRing and membership,
+	// which are replaced together when BlockStores are added or removed
+	ringMtx            sync.RWMutex
 	BlockStoreAddrs    []string
 	ConsistentHashRing *ConsistentHashRing
+	// metaLog journals updates when the MetaStore is persistent, nil
+	// when it only lives in memory
+	metaLog *metaLog
+	// numbers committed updates for WatchChanges
+	changes *changeFeed
+	// sequence number of the last change to each file, for GetChangesSince
+	fileSequences map[string]int64
+	// the previous versions of each file, oldest first, at most
+	// historySize of them
+	fileHistory map[string][]*FileMetaData
+	historySize int
+	// the BlockStores and ring settings, nil unless BlockStores were added
+	// or removed at runtime
+	membership *BlockStoreMembership
+	// moves blocks after BlockStores are added or removed
+	migrator *blockMigrator
 	UnimplementedMetaStoreServer
 }
 
 func (m *MetaStore) GetFileInfoMap(ctx context.Context, _ *emptypb.Empty) (*FileInfoMap, error) { //MY CODE
-	return &FileInfoMap{FileInfoMap: m.FileMetaMap}, nil
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return &FileInfoMap{FileInfoMap: copyFileMetaMap(m.FileMetaMap)}, nil
 }
 
+// UpdateFile is a compare-and-set on the file's version: it only succeeds
+// if fileMetaData is the version right after the current one, or the file
+// does not exist yet. Of two concurrent updates to the same version, one
+// fails with a version mismatch.
 func (m *MetaStore) UpdateFile(ctx context.Context, fileMetaData *FileMetaData) (*Version, error) { //MY CODE
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
 	fileName := fileMetaData.Filename
 	fileInfo, ok := m.FileMetaMap[fileName]
 	if !ok {
 		// fmt.Println("METASTORE: UPDATEFILE: File not found, creating new file")
-		newFile := &FileMetaData{Filename: fileMetaData.GetFilename(), Version: fileMetaData.GetVersion(), BlockHashList: fileMetaData.GetBlockHashList()}
-		m.FileMetaMap[fileName] = newFile
-		return &Version{Version: 1}, nil
+		if fileMetaData.Version < 1 {
+			return &Version{Version: -1}, status.Error(codes.Aborted, ERR_VERSION_MISMATCH.Error())
+		}
+	} else if fileInfo.Version != fileMetaData.Version-1 {
+		return &Version{Version: -1}, status.Error(codes.Aborted, ERR_VERSION_MISMATCH.Error())
+	}
+	// a copy, the caller's message may be reused once this returns
+	newFile := &FileMetaData{Filename: fileMetaData.GetFilename(), Version: fileMetaData.GetVersion(), BlockHashList: append([]string{}, fileMetaData.GetBlockHashList()...)}
+	if err := m.commit(newFile); err != nil {
+		return &Version{Version: -1}, err
+	}
+	return &Version{Version: newFile.Version}, nil
+}
+
+// copyFileMetaMap copies a map so it can be handed out after m.mtx is
+// released. The FileMetaData themselves are never changed once stored.
+func copyFileMetaMap(fileMetaMap map[string]*FileMetaData) map[string]*FileMetaData {
+	fileInfoMap := make(map[string]*FileMetaData, len(fileMetaMap))
+	for fileName, fileMetaData := range fileMetaMap {
+		fileInfoMap[fileName] = fileMetaData
+	}
+	return fileInfoMap
+}
+
+// commit journals fileMetaData to the write-ahead log (if any) before
+// applying it, so an acknowledged update is never lost. Must be called
+// with m.mtx held.
+func (m *MetaStore) commit(fileMetaData *FileMetaData) error {
+	if m.metaLog == nil {
+		m.apply(fileMetaData)
+		return nil
 	}
-	if fileInfo.Version != fileMetaData.Version-1 {
-		return &Version{Version: -1}, fmt.Errorf("Version mismatch")
+	if err := m.metaLog.append(fileMetaData); err != nil {
+		return fmt.Errorf("Error writing metastore log: %v", err)
+	}
+	m.apply(fileMetaData)
+	if m.metaLog.shouldSnapshot() {
+		// the update is already durable in the log, a failed
+		// snapshot only means the log keeps growing
+		if err := m.metaLog.snapshot(m.snapshot()); err != nil {
+			log.Println("Error snapshotting metastore:", err)
+		}
+	}
+	return nil
+}
 
+// apply makes fileMetaData the current version of its file, moving the
+// version it replaces into the file's history
+func (m *MetaStore) apply(fileMetaData *FileMetaData) {
+	fileName := fileMetaData.Filename
+	if previous, ok := m.FileMetaMap[fileName]; ok && m.historySize > 0 {
+		m.fileHistory[fileName] = trimHistory(append(m.fileHistory[fileName], previous), m.historySize)
 	}
 	m.FileMetaMap[fileName] = fileMetaData
-	return &Version{Version: fileMetaData.Version}, nil
+	m.fileSequences[fileName] = m.changes.publish(fileMetaData)
+}
+
+// replayRecord applies an update read back from the log. A crash between
+// writing a snapshot and truncating the log leaves entries that are
+// already part of the snapshot, those are skipped.
+func (m *MetaStore) replayRecord(fileMetaData *FileMetaData) {
+	if cur, ok := m.FileMetaMap[fileMetaData.Filename]; ok && cur.Version >= fileMetaData.Version {
+		return
+	}
+	m.apply(fileMetaData)
+}
+
+func (m *MetaStore) snapshot() *MetaSnapshot {
+	fileHistory := make(map[string]*FileVersions, len(m.fileHistory))
+	for fileName, versions := range m.fileHistory {
+		fileHistory[fileName] = &FileVersions{Versions: versions}
+	}
+	snapshot := &MetaSnapshot{
+		FileInfoMap:    m.FileMetaMap,
+		ChangeSequence: m.changes.lastSequence(),
+		FileSequences:  m.fileSequences,
+		FileHistory:    fileHistory,
+	}
+	m.ringMtx.RLock()
+	membership := m.membership
+	m.ringMtx.RUnlock()
+	if membership != nil {
+		snapshot.Membership = membership
+		snapshot.DrainingMemberships = m.migrator.drainingMemberships()
+	}
+	return snapshot
+}
+
+// restore replaces the MetaStore's state with snapshot. Must be called with
+// m.mtx held, or before the MetaStore is shared.
+func (m *MetaStore) restore(snapshot *MetaSnapshot) {
+	m.FileMetaMap = map[string]*FileMetaData{}
+	m.fileSequences = map[string]int64{}
+	m.fileHistory = map[string][]*FileMetaData{}
+	for fileName, fileMetaData := range snapshot.FileInfoMap {
+		m.FileMetaMap[fileName] = fileMetaData
+	}
+	for fileName, fileSequence := range snapshot.FileSequences {
+		m.fileSequences[fileName] = fileSequence
+	}
+	for fileName, versions := range snapshot.FileHistory {
+		if history := trimHistory(versions.Versions, m.historySize); len(history) > 0 {
+			m.fileHistory[fileName] = history
+		}
+	}
+	m.changes.reset(snapshot.ChangeSequence)
+	if snapshot.Membership != nil {
+		m.restoreMembership(snapshot.Membership, snapshot.DrainingMemberships)
+	}
+}
+
+// trimHistory keeps the newest historySize versions of history
+func trimHistory(history []*FileMetaData, historySize int) []*FileMetaData {
+	if len(history) > historySize {
+		return history[len(history)-historySize:]
+	}
+	return history
+}
+
+// ListFileVersions returns every retained version of a file, oldest first
+// and ending with the current version
+func (m *MetaStore) ListFileVersions(ctx context.Context, fileName *FileName) (*FileVersions, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	current, ok := m.FileMetaMap[fileName.Filename]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "File %s not found", fileName.Filename)
+	}
+	versions := make([]*FileMetaData, 0, len(m.fileHistory[fileName.Filename])+1)
+	versions = append(versions, m.fileHistory[fileName.Filename]...)
+	versions = append(versions, current)
+	return &FileVersions{Versions: versions}, nil
+}
+
+// GetFileVersion returns one retained version of a file
+func (m *MetaStore) GetFileVersion(ctx context.Context, fileVersion *FileVersion) (*FileMetaData, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	if current, ok := m.FileMetaMap[fileVersion.Filename]; ok && current.Version == fileVersion.Version {
+		return current, nil
+	}
+	for _, fileMetaData := range m.fileHistory[fileVersion.Filename] {
+		if fileMetaData.Version == fileVersion.Version {
+			return fileMetaData, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "Version %d of %s not found", fileVersion.Version, fileVersion.Filename)
 }
 
 // Given a list of block hashes,
@@ -40,29 +211,146 @@ func (m *MetaStore) UpdateFile(ctx context.Context, fileMetaData *FileMetaData)
 // Returns a mapping from block server address to block hashes.
 func (m *MetaStore) GetBlockStoreMap(ctx context.Context, blockHashesIn *BlockHashes) (*BlockStoreMap, error) {
 	BlockMap := map[string]*BlockHashes{}
+	BlockReplicas := map[string]*BlockStoreAddrs{}
+	ring, _ := m.hashRing()
 
 	for _, blockHash := range blockHashesIn.Hashes {
-		blockStoreAddr := m.ConsistentHashRing.GetResponsibleServer(blockHash)
-		if _, ok := BlockMap[blockStoreAddr]; !ok {
-			BlockMap[blockStoreAddr] = &BlockHashes{Hashes: []string{}}
+		blockStoreAddrs := ring.GetResponsibleServers(blockHash)
+		for _, blockStoreAddr := range blockStoreAddrs {
+			if _, ok := BlockMap[blockStoreAddr]; !ok {
+				BlockMap[blockStoreAddr] = &BlockHashes{Hashes: []string{}}
+			}
+			BlockMap[blockStoreAddr].Hashes = append(BlockMap[blockStoreAddr].Hashes, blockHash)
 		}
-		BlockMap[blockStoreAddr].Hashes = append(BlockMap[blockStoreAddr].Hashes, blockHash)
+		// while blocks are being migrated they may still be where an
+		// earlier ring placed them
+		BlockReplicas[blockHash] = &BlockStoreAddrs{BlockStoreAddrs: m.migrator.readReplicas(blockHash, blockStoreAddrs)}
 	}
 
-	return &BlockStoreMap{BlockStoreMap: BlockMap}, nil
+	return &BlockStoreMap{BlockStoreMap: BlockMap, BlockReplicas: BlockReplicas}, nil
 }
 
 func (m *MetaStore) GetBlockStoreAddrs(ctx context.Context, _ *emptypb.Empty) (*BlockStoreAddrs, error) {
-	return &BlockStoreAddrs{BlockStoreAddrs: m.BlockStoreAddrs}, nil
+	_, blockStoreAddrs := m.hashRing()
+	return &BlockStoreAddrs{BlockStoreAddrs: blockStoreAddrs}, nil
+}
+
+// hashRing returns the current ring and BlockStores. Rings are replaced
+// rather than changed, so the ring stays usable after the lock is released.
+func (m *MetaStore) hashRing() (*ConsistentHashRing, []string) {
+	m.ringMtx.RLock()
+	defer m.ringMtx.RUnlock()
+	return m.ConsistentHashRing, m.BlockStoreAddrs
+}
+
+// WatchChanges streams every update committed after
+// request.AfterSequence, then keeps streaming new updates as they are
+// committed until the client goes away. It fails with OutOfRange when the
+// requested changes are no longer retained, the client then has to fall
+// back to GetFileInfoMap.
+func (m *MetaStore) WatchChanges(request *WatchRequest, stream MetaStore_WatchChangesServer) error {
+	afterSequence := request.AfterSequence
+	if afterSequence < 0 {
+		afterSequence = m.changes.lastSequence()
+	}
+	for {
+		changes, notify, err := m.changes.since(afterSequence)
+		if err != nil {
+			return status.Error(codes.OutOfRange, err.Error())
+		}
+		for _, change := range changes {
+			if err := stream.Send(change); err != nil {
+				return err
+			}
+			afterSequence = change.Sequence
+		}
+		select {
+		case <-notify:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// GetChangesSince returns the files changed after cursor.Cursor and the
+// cursor to pass next time. Without a usable cursor (0, or one this
+// MetaStore never handed out) it returns every file and sets Full.
+func (m *MetaStore) GetChangesSince(ctx context.Context, cursor *ChangeCursor) (*FileInfoChanges, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	// updates are published under m.mtx, so no change can slip in
+	// between reading the sequence and scanning the files
+	sequence := m.changes.lastSequence()
+	if cursor.Cursor <= 0 || cursor.Cursor > sequence {
+		return &FileInfoChanges{FileInfoMap: copyFileMetaMap(m.FileMetaMap), Cursor: sequence, Full: true}, nil
+	}
+	changed := map[string]*FileMetaData{}
+	for fileName, fileSequence := range m.fileSequences {
+		if fileSequence > cursor.Cursor {
+			changed[fileName] = m.FileMetaMap[fileName]
+		}
+	}
+	return &FileInfoChanges{FileInfoMap: changed, Cursor: sequence}, nil
+}
+
+// GetReferencedBlockHashes returns the hashes of every block used by the
+// current or a retained previous version of any file, the blocks garbage
+// collection must keep
+func (m *MetaStore) GetReferencedBlockHashes(ctx context.Context, _ *emptypb.Empty) (*BlockHashes, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	referenced := map[string]bool{}
+	mark := func(fileMetaData *FileMetaData) {
+		for _, hash := range fileMetaData.BlockHashList {
+			if hash != TOMBSTONE_HASHVALUE && hash != EMPTYFILE_HASHVALUE {
+				referenced[hash] = true
+			}
+		}
+	}
+	for fileName, fileMetaData := range m.FileMetaMap {
+		mark(fileMetaData)
+		for _, previous := range m.fileHistory[fileName] {
+			mark(previous)
+		}
+	}
+	blockHashes := &BlockHashes{Hashes: make([]string, 0, len(referenced))}
+	for hash := range referenced {
+		blockHashes.Hashes = append(blockHashes.Hashes, hash)
+	}
+	return blockHashes, nil
 }
 
 // This line guarantees all method for MetaStore are implemented
 var _ MetaStoreInterface = new(MetaStore)
 
-func NewMetaStore(blockStoreAddrs []string) *MetaStore {
-	return &MetaStore{
+// Create a MetaStore that keeps historySize previous versions of each
+// file. If metaDir is not empty the MetaStore is persisted there: the
+// latest snapshot and the write-ahead log are replayed so file versions
+// survive a restart or crash.
+func NewMetaStore(blockStoreAddrs []string, metaDir string, historySize int) (*MetaStore, error) {
+	metaStore := &MetaStore{
 		FileMetaMap:        map[string]*FileMetaData{},
 		BlockStoreAddrs:    blockStoreAddrs,
 		ConsistentHashRing: NewConsistentHashRing(blockStoreAddrs),
+		changes:            newChangeFeed(0),
+		fileSequences:      map[string]int64{},
+		fileHistory:        map[string][]*FileMetaData{},
+		historySize:        historySize,
+		migrator:           newBlockMigrator(),
+	}
+	if metaDir == "" {
+		return metaStore, nil
+	}
+	metaLog, err := openMetaLog(metaDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := metaLog.replay(metaStore.restore, metaStore.replayRecord); err != nil {
+		metaLog.close()
+		return nil, err
 	}
+	metaStore.metaLog = metaLog
+	// finish a migration cut short by a restart
+	metaStore.migrator.start()
+	return metaStore, nil
 }
@@ -0,0 +1,77 @@
+package surfstore
+
+import (
+	"errors"
+	"sync"
+)
+
+var ERR_CHANGES_UNAVAILABLE = errors.New("Changes after this sequence number are no longer available")
+
+// changeFeed numbers every update committed to a MetaStore and keeps the
+// most recent CHANGE_HISTORY_SIZE of them, so a watcher that reconnects
+// can pick up where it left off.
+type changeFeed struct {
+	mtx sync.Mutex
+	// sequence number of the last change
+	sequence int64
+	history  []*FileChange
+	// closed and replaced on every change to wake up watchers
+	notify chan struct{}
+}
+
+func newChangeFeed(sequence int64) *changeFeed {
+	return &changeFeed{sequence: sequence, notify: make(chan struct{})}
+}
+
+// publish assigns the next sequence number to fileMetaData's update and
+// returns it
+func (f *changeFeed) publish(fileMetaData *FileMetaData) int64 {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.sequence++
+	change := &FileChange{
+		Sequence: f.sequence,
+		Filename: fileMetaData.Filename,
+		Version:  fileMetaData.Version,
+		Deleted:  len(fileMetaData.BlockHashList) == 1 && fileMetaData.BlockHashList[0] == TOMBSTONE_HASHVALUE,
+	}
+	f.history = append(f.history, change)
+	if len(f.history) > CHANGE_HISTORY_SIZE {
+		f.history = f.history[len(f.history)-CHANGE_HISTORY_SIZE:]
+	}
+	close(f.notify)
+	f.notify = make(chan struct{})
+	return f.sequence
+}
+
+// reset restarts the feed at sequence with no history, watchers are woken
+// up and find their changes unavailable
+func (f *changeFeed) reset(sequence int64) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.sequence = sequence
+	f.history = nil
+	close(f.notify)
+	f.notify = make(chan struct{})
+}
+
+func (f *changeFeed) lastSequence() int64 {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.sequence
+}
+
+// since returns the changes after afterSequence and a channel that is
+// closed on the next change. It fails with ERR_CHANGES_UNAVAILABLE when
+// some of those changes have dropped out of the history.
+func (f *changeFeed) since(afterSequence int64) ([]*FileChange, <-chan struct{}, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if afterSequence > f.sequence || afterSequence < f.sequence-int64(len(f.history)) {
+		return nil, nil, ERR_CHANGES_UNAVAILABLE
+	}
+	start := len(f.history) - int(f.sequence-afterSequence)
+	changes := make([]*FileChange, len(f.history)-start)
+	copy(changes, f.history[start:])
+	return changes, f.notify, nil
+}
@@ -0,0 +1,128 @@
+package surfstore
+
+import (
+	context "context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errStopWatching = errors.New("stop watching")
+
+func startChangesServer(t *testing.T) (*MetaStore, *RPCClient) {
+	t.Helper()
+	metaStore, err := NewMetaStore(nil, "", DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterMetaStoreServer(server, metaStore)
+	})
+	client := NewSurfstoreRPCClient(addr, "", 0)
+	t.Cleanup(func() { client.Close() })
+	return metaStore, client
+}
+
+func commitVersion(t *testing.T, metaStore *MetaStore, fileName string, version int32) {
+	t.Helper()
+	if _, err := metaStore.UpdateFile(context.Background(), &FileMetaData{Filename: fileName, Version: version, BlockHashList: []string{"h"}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The stream replays the retained changes after the requested sequence
+// number, then the ones committed while it is open
+func TestWatchChangesStream(t *testing.T) {
+	metaStore, client := startChangesServer(t)
+	commitVersion(t, metaStore, "a.txt", 1)
+	commitVersion(t, metaStore, "b.txt", 1)
+	commitVersion(t, metaStore, "b.txt", 2)
+
+	got := []*FileChange{}
+	err := client.WatchChanges(context.Background(), 1, func(change *FileChange) error {
+		got = append(got, change)
+		if len(got) == 2 {
+			// committed while the stream is open
+			commitVersion(t, metaStore, "c.txt", 1)
+		}
+		if len(got) == 3 {
+			return errStopWatching
+		}
+		return nil
+	})
+	if !errors.Is(err, errStopWatching) {
+		t.Fatal(err)
+	}
+	want := []string{"b.txt v1", "b.txt v2", "c.txt v1"}
+	for i, change := range got {
+		if change.Sequence != int64(i+2) || change.Filename+" v"+strconv.Itoa(int(change.Version)) != want[i] {
+			t.Fatalf("change %d is %v, want %s at sequence %d", i, change, want[i], i+2)
+		}
+	}
+}
+
+// A negative sequence number only streams what is committed from now on
+func TestWatchChangesFromNow(t *testing.T) {
+	metaStore, client := startChangesServer(t)
+	commitVersion(t, metaStore, "old.txt", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan *FileChange, 1)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- client.WatchChanges(ctx, -1, func(change *FileChange) error {
+			changes <- change
+			return errStopWatching
+		})
+	}()
+	// the stream may open before or after an update, keep committing until
+	// one is streamed
+	for version := int32(1); ; version++ {
+		commitVersion(t, metaStore, "new.txt", version)
+		select {
+		case err := <-watchErr:
+			if !errors.Is(err, errStopWatching) {
+				t.Fatal(err)
+			}
+			if change := <-changes; change.Filename != "new.txt" {
+				t.Fatalf("streamed %v, committed before the stream opened", change)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// Asking for changes that were trimmed from the history fails with
+// OutOfRange, so the client knows to fall back to a full sync
+func TestWatchChangesOutOfRange(t *testing.T) {
+	metaStore, client := startChangesServer(t)
+	for version := int32(1); version <= int32(CHANGE_HISTORY_SIZE+5); version++ {
+		commitVersion(t, metaStore, "f.txt", version)
+	}
+	for _, afterSequence := range []int64{0, 4, int64(CHANGE_HISTORY_SIZE + 6)} {
+		err := client.WatchChanges(context.Background(), afterSequence, func(change *FileChange) error {
+			return errStopWatching
+		})
+		if status.Code(err) != codes.OutOfRange {
+			t.Fatalf("watching after sequence %d: got %v, want OutOfRange", afterSequence, err)
+		}
+	}
+
+	// the oldest change still retained
+	err := client.WatchChanges(context.Background(), 5, func(change *FileChange) error {
+		if change.Sequence != 6 {
+			t.Fatalf("first change streamed is %d, want 6", change.Sequence)
+		}
+		return errStopWatching
+	})
+	if !errors.Is(err, errStopWatching) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,214 @@
+package surfstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// metaLog is the write-ahead log backing a persistent MetaStore. Every
+// update is appended (and fsynced) to META_LOG_FILENAME before it is
+// applied. Every SNAPSHOT_INTERVAL entries the MetaStore's whole state is
+// written to META_SNAPSHOT_FILENAME and the log is truncated.
+//
+// Each log record is framed as a 4 byte length, a 4 byte CRC32 of the
+// payload and the marshalled FileMetaData, so a torn write at the tail of
+// the log is detected and dropped on replay.
+type metaLog struct {
+	mtx     sync.Mutex
+	dir     string
+	file    logFile
+	entries int
+	// set once a failed append could not be undone, the log may hold a
+	// record its caller was told failed and takes no more appends
+	failed error
+}
+
+// logFile is the part of *os.File the log uses
+type logFile interface {
+	io.ReadWriteSeeker
+	io.Closer
+	Sync() error
+	Truncate(size int64) error
+}
+
+const logHeaderSize int = 8
+
+func openMetaLog(dir string) (*metaLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, META_LOG_FILENAME), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &metaLog{dir: dir, file: file}, nil
+}
+
+// replay hands the snapshot, if there is one, to restore and then every
+// intact record in the log to apply, in order. A trailing partial record is
+// truncated away.
+func (l *metaLog) replay(restore func(snapshot *MetaSnapshot), apply func(fileMetaData *FileMetaData)) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	snapshot, err := os.ReadFile(filepath.Join(l.dir, META_SNAPSHOT_FILENAME))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		metaSnapshot := &MetaSnapshot{}
+		if err := proto.Unmarshal(snapshot, metaSnapshot); err != nil {
+			return fmt.Errorf("corrupt metastore snapshot: %v", err)
+		}
+		restore(metaSnapshot)
+	}
+
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(l.file)
+	var offset int64
+	for {
+		fileMetaData := &FileMetaData{}
+		n, err := readLogRecord(reader, fileMetaData)
+		if err != nil {
+			if err != io.EOF {
+				// torn or corrupt tail left by a crash, drop it
+				if err := l.file.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		offset += int64(n)
+		l.entries++
+		apply(fileMetaData)
+	}
+	_, err = l.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// readLogRecord reads one framed record from reader into msg and returns
+// the number of bytes it took up.
+func readLogRecord(reader io.Reader, msg proto.Message) (int, error) {
+	header := make([]byte, logHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, errors.New("truncated log record")
+		}
+		return 0, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > MAX_LOG_RECORD_SIZE {
+		// a torn or corrupt header, the payload cannot be this big
+		return 0, fmt.Errorf("log record of %d bytes is too large", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return 0, errors.New("truncated log record")
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return 0, errors.New("log record checksum mismatch")
+	}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return 0, err
+	}
+	return logHeaderSize + len(payload), nil
+}
+
+// encodeLogRecord frames msg as a log record.
+func encodeLogRecord(msg proto.Message) ([]byte, error) {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	record := make([]byte, logHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[logHeaderSize:], payload)
+	return record, nil
+}
+
+// append durably journals fileMetaData. It only returns once the record
+// has been fsynced. A record that fails to be written or fsynced is
+// truncated away again, so it is not replayed after the caller was told
+// the update failed.
+func (l *metaLog) append(fileMetaData *FileMetaData) error {
+	record, err := encodeLogRecord(fileMetaData)
+	if err != nil {
+		return err
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.failed != nil {
+		return fmt.Errorf("log unusable after an earlier failed append: %v", l.failed)
+	}
+	offset, err := l.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	_, err = l.file.Write(record)
+	if err == nil {
+		err = l.file.Sync()
+	}
+	if err != nil {
+		if undoErr := l.truncate(offset); undoErr != nil {
+			l.failed = undoErr
+		}
+		return err
+	}
+	l.entries++
+	return nil
+}
+
+// truncate cuts the log back to offset and fsyncs it
+func (l *metaLog) truncate(offset int64) error {
+	if err := l.file.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := l.file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+func (l *metaLog) shouldSnapshot() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.entries >= SNAPSHOT_INTERVAL
+}
+
+// snapshot atomically replaces the snapshot file and truncates the log.
+func (l *metaLog) snapshot(metaSnapshot *MetaSnapshot) error {
+	data, err := proto.Marshal(metaSnapshot)
+	if err != nil {
+		return err
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if err := writeFileAtomic(filepath.Join(l.dir, META_SNAPSHOT_FILENAME), data); err != nil {
+		return err
+	}
+	if err := l.truncate(0); err != nil {
+		return err
+	}
+	l.entries = 0
+	return nil
+}
+
+func (l *metaLog) close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.file.Close()
+}
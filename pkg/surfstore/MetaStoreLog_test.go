@@ -0,0 +1,240 @@
+package surfstore
+
+import (
+	context "context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func logTestRecord(fileName string, version int32) *FileMetaData {
+	return &FileMetaData{Filename: fileName, Version: version, BlockHashList: []string{fileName + "-hash"}}
+}
+
+// replayLog reopens the log in dir and returns what replay hands back
+func replayLog(t *testing.T, dir string) (*MetaSnapshot, []*FileMetaData, *metaLog) {
+	t.Helper()
+	l, err := openMetaLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.close() })
+	var snapshot *MetaSnapshot
+	records := []*FileMetaData{}
+	err = l.replay(func(s *MetaSnapshot) { snapshot = s }, func(fileMetaData *FileMetaData) {
+		records = append(records, fileMetaData)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snapshot, records, l
+}
+
+func appendRecords(t *testing.T, l *metaLog, records ...*FileMetaData) {
+	t.Helper()
+	for _, record := range records {
+		if err := l.append(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func assertRecords(t *testing.T, got []*FileMetaData, want ...*FileMetaData) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("replayed %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Filename != want[i].Filename || got[i].Version != want[i].Version {
+			t.Fatalf("record %d is %s v%d, want %s v%d", i, got[i].Filename, got[i].Version, want[i].Filename, want[i].Version)
+		}
+	}
+}
+
+// Records come back in the order they were appended, and appends after a
+// replay land after them
+func TestMetaLogReplay(t *testing.T) {
+	dir := t.TempDir()
+	_, _, l := replayLog(t, dir)
+	first := []*FileMetaData{logTestRecord("a.txt", 1), logTestRecord("b.txt", 1), logTestRecord("a.txt", 2)}
+	appendRecords(t, l, first...)
+	l.close()
+
+	snapshot, records, l := replayLog(t, dir)
+	if snapshot != nil {
+		t.Fatal("restored a snapshot that was never taken")
+	}
+	assertRecords(t, records, first...)
+	appendRecords(t, l, logTestRecord("c.txt", 1))
+	l.close()
+
+	_, records, _ = replayLog(t, dir)
+	assertRecords(t, records, append(first, logTestRecord("c.txt", 1))...)
+}
+
+// A record cut short by a crash, or whose header is garbage, is dropped
+// with everything after it and later appends follow the intact records
+func TestMetaLogTornTail(t *testing.T) {
+	hugeHeader := make([]byte, logHeaderSize)
+	binary.BigEndian.PutUint32(hugeHeader[0:4], 0xFFFFFFFF)
+	record, err := encodeLogRecord(logTestRecord("torn.txt", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipped := append([]byte{}, record...)
+	flipped[len(flipped)-1] ^= 0xFF
+	tails := map[string][]byte{
+		"partial header":  record[:logHeaderSize-3],
+		"partial payload": record[:len(record)-2],
+		"bad checksum":    flipped,
+		"huge length":     append(hugeHeader, record...),
+	}
+	for name, tail := range tails {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			_, _, l := replayLog(t, dir)
+			intact := []*FileMetaData{logTestRecord("a.txt", 1), logTestRecord("a.txt", 2)}
+			appendRecords(t, l, intact...)
+			l.close()
+			logFile, err := os.OpenFile(filepath.Join(dir, META_LOG_FILENAME), os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := logFile.Write(tail); err != nil {
+				t.Fatal(err)
+			}
+			logFile.Close()
+
+			_, records, l := replayLog(t, dir)
+			assertRecords(t, records, intact...)
+			appendRecords(t, l, logTestRecord("b.txt", 1))
+			l.close()
+
+			_, records, _ = replayLog(t, dir)
+			assertRecords(t, records, append(intact, logTestRecord("b.txt", 1))...)
+		})
+	}
+}
+
+// A snapshot replaces everything logged before it, only the records
+// appended after it are replayed on top
+func TestMetaLogSnapshotTruncates(t *testing.T) {
+	dir := t.TempDir()
+	_, _, l := replayLog(t, dir)
+	appendRecords(t, l, logTestRecord("a.txt", 1), logTestRecord("a.txt", 2))
+	snapshot := &MetaSnapshot{
+		FileInfoMap:    map[string]*FileMetaData{"a.txt": logTestRecord("a.txt", 2)},
+		ChangeSequence: 2,
+	}
+	if err := l.snapshot(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, META_LOG_FILENAME)); err != nil || info.Size() != 0 {
+		t.Fatalf("log not truncated by the snapshot (%v)", err)
+	}
+	appendRecords(t, l, logTestRecord("b.txt", 1))
+	l.close()
+
+	restored, records, _ := replayLog(t, dir)
+	if restored == nil {
+		t.Fatal("snapshot not restored")
+	}
+	if restored.ChangeSequence != 2 || restored.FileInfoMap["a.txt"].Version != 2 {
+		t.Fatalf("restored snapshot %v, want a.txt at version 2 and change 2", restored)
+	}
+	assertRecords(t, records, logTestRecord("b.txt", 1))
+}
+
+// failingLogFile is a log file whose next fsync, and truncates, can be
+// made to fail
+type failingLogFile struct {
+	*os.File
+	failSync     bool
+	failTruncate bool
+}
+
+func (f *failingLogFile) Sync() error {
+	if f.failSync {
+		f.failSync = false
+		return errors.New("fsync failed")
+	}
+	return f.File.Sync()
+}
+
+func (f *failingLogFile) Truncate(size int64) error {
+	if f.failTruncate {
+		return errors.New("truncate failed")
+	}
+	return f.File.Truncate(size)
+}
+
+// An update whose record could not be fsynced fails and is not replayed
+// after a restart, later updates are logged as usual
+func TestMetaStoreFailedAppend(t *testing.T) {
+	dir := t.TempDir()
+	metaStore, err := NewMetaStore(nil, dir, DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := metaStore.UpdateFile(ctx, logTestRecord("f.txt", 1)); err != nil {
+		t.Fatal(err)
+	}
+	logFile := &failingLogFile{File: metaStore.metaLog.file.(*os.File), failSync: true}
+	metaStore.metaLog.file = logFile
+	if _, err := metaStore.UpdateFile(ctx, logTestRecord("f.txt", 2)); err == nil {
+		t.Fatal("an update that could not be fsynced succeeded")
+	}
+	if got := metaStore.FileMetaMap["f.txt"].Version; got != 1 {
+		t.Fatalf("f.txt at version %d after a failed update, want 1", got)
+	}
+	if _, err := metaStore.UpdateFile(ctx, logTestRecord("g.txt", 1)); err != nil {
+		t.Fatal(err)
+	}
+	metaStore.metaLog.close()
+
+	_, records, _ := replayLog(t, dir)
+	assertRecords(t, records, logTestRecord("f.txt", 1), logTestRecord("g.txt", 1))
+}
+
+// A log that cannot cut a failed record off again takes no more updates
+func TestMetaLogUnusableAfterFailedUndo(t *testing.T) {
+	dir := t.TempDir()
+	_, _, l := replayLog(t, dir)
+	logFile := &failingLogFile{File: l.file.(*os.File), failSync: true, failTruncate: true}
+	l.file = logFile
+	if err := l.append(logTestRecord("a.txt", 1)); err == nil {
+		t.Fatal("an append that could not be fsynced succeeded")
+	}
+	logFile.failTruncate = false
+	if err := l.append(logTestRecord("b.txt", 1)); err == nil {
+		t.Fatal("the log took an append after a failed record was left in it")
+	}
+}
+
+// A persistent MetaStore comes back with every update, across snapshots
+func TestMetaStoreReplaysLog(t *testing.T) {
+	dir := t.TempDir()
+	metaStore, err := NewMetaStore(nil, dir, DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := SNAPSHOT_INTERVAL + 5
+	for version := int32(1); version <= int32(updates); version++ {
+		if _, err := metaStore.UpdateFile(context.Background(), logTestRecord("f.txt", version)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	metaStore.metaLog.close()
+
+	restarted, err := NewMetaStore(nil, dir, DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.metaLog.close()
+	if got := restarted.FileMetaMap["f.txt"].Version; got != int32(updates) {
+		t.Fatalf("f.txt at version %d after a restart, want %d", got, updates)
+	}
+}
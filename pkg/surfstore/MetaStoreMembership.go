@@ -0,0 +1,153 @@
+package surfstore
+
+import (
+	context "context"
+	"fmt"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AddBlockStore adds a BlockStore to the hash ring. The blocks the ring
+// now places on it are moved there in the background, until then they are
+// still read from where they were.
+func (m *MetaStore) AddBlockStore(ctx context.Context, member *BlockStoreMember) (*BlockStoreAddrs, error) {
+	// one change at a time, and no update may land in the snapshot
+	// changeMembership writes while it is being taken
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	membership, err := m.membershipWith(member)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.changeMembership(membership); err != nil {
+		return nil, err
+	}
+	return &BlockStoreAddrs{BlockStoreAddrs: membershipAddrs(membership)}, nil
+}
+
+// RemoveBlockStore takes a BlockStore off the hash ring. Its blocks are
+// moved to the BlockStores that take over in the background, it has to
+// stay up until that is done.
+func (m *MetaStore) RemoveBlockStore(ctx context.Context, member *BlockStoreMember) (*BlockStoreAddrs, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	membership, err := m.membershipWithout(member.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.changeMembership(membership); err != nil {
+		return nil, err
+	}
+	return &BlockStoreAddrs{BlockStoreAddrs: membershipAddrs(membership)}, nil
+}
+
+// membershipWith returns the current membership with member added
+func (m *MetaStore) membershipWith(member *BlockStoreMember) (*BlockStoreMembership, error) {
+	if member.Addr == "" || member.Weight < 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid BlockStore")
+	}
+	ring, blockStoreAddrs := m.hashRing()
+	membership := ring.membership(blockStoreAddrs)
+	for _, existing := range membership.Members {
+		if existing.Addr == member.Addr {
+			return nil, status.Errorf(codes.AlreadyExists, "BlockStore %s is already on the ring", member.Addr)
+		}
+	}
+	weight := member.Weight
+	if weight == 0 {
+		weight = 1
+	}
+	membership.Members = append(membership.Members, &BlockStoreMember{Addr: member.Addr, Weight: weight})
+	return membership, nil
+}
+
+// membershipWithout returns the current membership with blockStoreAddr
+// removed
+func (m *MetaStore) membershipWithout(blockStoreAddr string) (*BlockStoreMembership, error) {
+	ring, blockStoreAddrs := m.hashRing()
+	membership := ring.membership(blockStoreAddrs)
+	members := make([]*BlockStoreMember, 0, len(membership.Members))
+	for _, member := range membership.Members {
+		if member.Addr != blockStoreAddr {
+			members = append(members, member)
+		}
+	}
+	if len(members) == len(membership.Members) {
+		return nil, status.Errorf(codes.NotFound, "BlockStore %s is not on the ring", blockStoreAddr)
+	}
+	if len(members) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "Cannot remove the last BlockStore")
+	}
+	membership.Members = members
+	return membership, nil
+}
+
+// changeMembership persists membership, when the MetaStore is persistent,
+// then places blocks by it and starts moving the blocks that changed place.
+// Must be called with m.mtx held.
+func (m *MetaStore) changeMembership(membership *BlockStoreMembership) error {
+	if m.metaLog != nil {
+		snapshot := m.snapshot()
+		snapshot.Membership = membership
+		ring, blockStoreAddrs := m.hashRing()
+		snapshot.DrainingMemberships = append(snapshot.DrainingMemberships, ring.membership(blockStoreAddrs))
+		if err := m.metaLog.snapshot(snapshot); err != nil {
+			return fmt.Errorf("Error persisting BlockStores: %v", err)
+		}
+	}
+	m.setMembership(membership)
+	m.migrator.start()
+	return nil
+}
+
+// setMembership places blocks by membership from now on, the blocks placed
+// by the previous ring are left to the migrator
+func (m *MetaStore) setMembership(membership *BlockStoreMembership) {
+	ring := newRingFromMembership(membership)
+	m.ringMtx.Lock()
+	defer m.ringMtx.Unlock()
+	// the previous ring is draining before anyone gets the new one
+	m.migrator.ringChanged(m.ConsistentHashRing, ring)
+	m.ConsistentHashRing = ring
+	m.BlockStoreAddrs = membershipAddrs(membership)
+	m.membership = membership
+}
+
+// restoreMembership brings back the BlockStores persisted by a snapshot and
+// the rings a migration was still draining
+func (m *MetaStore) restoreMembership(membership *BlockStoreMembership, drainingMemberships []*BlockStoreMembership) {
+	ring := newRingFromMembership(membership)
+	draining := make([]*ConsistentHashRing, 0, len(drainingMemberships))
+	for _, drainingMembership := range drainingMemberships {
+		draining = append(draining, newRingFromMembership(drainingMembership))
+	}
+	m.ringMtx.Lock()
+	defer m.ringMtx.Unlock()
+	m.migrator.restore(draining, ring)
+	m.ConsistentHashRing = ring
+	m.BlockStoreAddrs = membershipAddrs(membership)
+	m.membership = membership
+}
+
+// SetConsistentHashRing replaces the ring blocks are mapped to BlockStores
+// with. A MetaStore that restored BlockStores added or removed at runtime
+// keeps its own ring.
+func (m *MetaStore) SetConsistentHashRing(ring *ConsistentHashRing) {
+	m.ringMtx.Lock()
+	defer m.ringMtx.Unlock()
+	if m.membership != nil {
+		log.Println("Using the BlockStores persisted by the MetaStore:", m.BlockStoreAddrs)
+		return
+	}
+	m.ConsistentHashRing = ring
+}
+
+func membershipAddrs(membership *BlockStoreMembership) []string {
+	blockStoreAddrs := make([]string, 0, len(membership.Members))
+	for _, member := range membership.Members {
+		blockStoreAddrs = append(blockStoreAddrs, member.Addr)
+	}
+	return blockStoreAddrs
+}
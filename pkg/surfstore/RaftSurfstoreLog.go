@@ -0,0 +1,156 @@
+package surfstore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// raftStorage persists the state a Raft server must not lose across a
+// crash: its current term and vote in RAFT_STATE_FILENAME, its log in
+// RAFT_LOG_FILENAME using the same record framing as the MetaStore's
+// write-ahead log, and the snapshot the log was last compacted into in
+// RAFT_SNAPSHOT_FILENAME.
+type raftStorage struct {
+	dir     string
+	logFile *os.File
+}
+
+func openRaftStorage(dir string) (*raftStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	logFile, err := os.OpenFile(filepath.Join(dir, RAFT_LOG_FILENAME), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &raftStorage{dir: dir, logFile: logFile}, nil
+}
+
+// load returns the persisted term, vote, snapshot (nil if the log was
+// never compacted) and the log entries after the snapshot. A torn record at
+// the tail of the log is truncated away; it was never acknowledged.
+func (s *raftStorage) load() (*RaftState, *RaftSnapshot, []*UpdateOperation, error) {
+	state := &RaftState{Term: 0, VotedFor: -1}
+	data, err := os.ReadFile(filepath.Join(s.dir, RAFT_STATE_FILENAME))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, nil, err
+	}
+	if err == nil {
+		if err := proto.Unmarshal(data, state); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	var snapshot *RaftSnapshot
+	data, err = os.ReadFile(filepath.Join(s.dir, RAFT_SNAPSHOT_FILENAME))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, nil, err
+	}
+	if err == nil {
+		snapshot = &RaftSnapshot{}
+		if err := proto.Unmarshal(data, snapshot); err != nil {
+			return nil, nil, nil, fmt.Errorf("corrupt raft snapshot: %v", err)
+		}
+	}
+
+	if _, err := s.logFile.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, nil, err
+	}
+	reader := bufio.NewReader(s.logFile)
+	entries := []*UpdateOperation{}
+	var offset int64
+	for {
+		entry := &UpdateOperation{}
+		n, err := readLogRecord(reader, entry)
+		if err != nil {
+			if err != io.EOF {
+				if err := s.logFile.Truncate(offset); err != nil {
+					return nil, nil, nil, err
+				}
+			}
+			break
+		}
+		offset += int64(n)
+		if snapshot == nil {
+			// logs written before there were snapshots do not number
+			// their entries
+			entry.Index = int64(len(entries))
+		} else if entry.Index <= snapshot.LastIndex {
+			// a crash between writing the snapshot and rewriting the
+			// log leaves entries the snapshot already covers
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if _, err := s.logFile.Seek(offset, io.SeekStart); err != nil {
+		return nil, nil, nil, err
+	}
+	return state, snapshot, entries, nil
+}
+
+func (s *raftStorage) saveState(term int64, votedFor int64) error {
+	data, err := proto.Marshal(&RaftState{Term: term, VotedFor: votedFor})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.dir, RAFT_STATE_FILENAME), data)
+}
+
+// appendEntries durably appends entries to the end of the log.
+func (s *raftStorage) appendEntries(entries []*UpdateOperation) error {
+	for _, entry := range entries {
+		record, err := encodeLogRecord(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := s.logFile.Write(record); err != nil {
+			return err
+		}
+	}
+	return s.logFile.Sync()
+}
+
+// rewriteLog replaces the whole log, used when conflicting entries are
+// truncated.
+func (s *raftStorage) rewriteLog(entries []*UpdateOperation) error {
+	data := []byte{}
+	for _, entry := range entries {
+		record, err := encodeLogRecord(entry)
+		if err != nil {
+			return err
+		}
+		data = append(data, record...)
+	}
+	logPath := filepath.Join(s.dir, RAFT_LOG_FILENAME)
+	if err := writeFileAtomic(logPath, data); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	s.logFile.Close()
+	s.logFile = logFile
+	return nil
+}
+
+// saveSnapshot compacts the log: it atomically replaces the snapshot, then
+// rewrites the log with only the entries that come after it.
+func (s *raftStorage) saveSnapshot(snapshot *RaftSnapshot, entries []*UpdateOperation) error {
+	data, err := proto.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(s.dir, RAFT_SNAPSHOT_FILENAME), data); err != nil {
+		return err
+	}
+	return s.rewriteLog(entries)
+}
+
+func (s *raftStorage) close() error {
+	return s.logFile.Close()
+}
@@ -0,0 +1,905 @@
+package surfstore
+
+import (
+	context "context"
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+var ERR_NOT_LEADER = errors.New("Server is not the leader")
+var ERR_NO_MAJORITY = errors.New("Could not reach a majority of MetaStore replicas")
+var ERR_LEADER_NOT_READY = errors.New("Leader has not caught up with its log yet")
+
+type raftRole int
+
+const (
+	follower raftRole = iota
+	candidate
+	leader
+)
+
+type applyResult struct {
+	version *Version
+	err     error
+}
+
+// RaftSurfstore is a MetaStore replicated across a cluster of servers with
+// Raft. UpdateFile is appended to the leader's log and only applied to the
+// MetaStore once a majority of the cluster has stored it. Followers reject
+// every MetaStore call with ERR_NOT_LEADER and name the leader (when they
+// know it) in the LEADER_ADDR_KEY trailer.
+//
+// Every SNAPSHOT_INTERVAL applied entries the log is compacted into a
+// snapshot of the MetaStore. Log indexes keep counting the compacted
+// entries, a follower that needs entries the leader no longer has is sent
+// the snapshot instead.
+type RaftSurfstore struct {
+	id        int64
+	peers     []string
+	metaStore *MetaStore
+	storage   *raftStorage
+	clients   []RaftSurfstoreClient
+	conns     []*grpc.ClientConn
+	stop      chan struct{}
+
+	mtx      sync.Mutex
+	role     raftRole
+	term     int64
+	votedFor int64
+	leaderId int64
+	// the entries after snapshot, nil until the log is first compacted
+	snapshot         *RaftSnapshot
+	log              []*UpdateOperation
+	commitIndex      int64
+	lastApplied      int64
+	termStartIndex   int64
+	nextIndex        []int64
+	matchIndex       []int64
+	electionDeadline time.Time
+	lastHeartbeat    time.Time
+	pending          map[int64]chan applyResult
+
+	UnimplementedRaftSurfstoreServer
+	UnimplementedMetaStoreServer
+}
+
+func (r *RaftSurfstore) GetFileInfoMap(ctx context.Context, empty *emptypb.Empty) (*FileInfoMap, error) {
+	if err := r.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	fileInfoMap := map[string]*FileMetaData{}
+	for fileName, fileMetaData := range r.metaStore.FileMetaMap {
+		fileInfoMap[fileName] = fileMetaData
+	}
+	return &FileInfoMap{FileInfoMap: fileInfoMap}, nil
+}
+
+func (r *RaftSurfstore) UpdateFile(ctx context.Context, fileMetaData *FileMetaData) (*Version, error) {
+	r.mtx.Lock()
+	if r.role != leader {
+		err := r.notLeaderError(ctx)
+		r.mtx.Unlock()
+		return nil, err
+	}
+	entry := &UpdateOperation{Term: r.term, FileMetaData: fileMetaData}
+	return r.propose(ctx, entry)
+}
+
+// propose appends entry to the leader's log and waits until it has been
+// committed and applied. Must be called with r.mtx held by the leader, it
+// is released before waiting.
+func (r *RaftSurfstore) propose(ctx context.Context, entry *UpdateOperation) (*Version, error) {
+	entry.Index = r.logEnd()
+	if r.storage != nil {
+		if err := r.storage.appendEntries([]*UpdateOperation{entry}); err != nil {
+			r.mtx.Unlock()
+			return nil, err
+		}
+	}
+	r.log = append(r.log, entry)
+	index := entry.Index
+	result := make(chan applyResult, 1)
+	r.pending[index] = result
+	r.advanceCommitIndex()
+	r.mtx.Unlock()
+
+	go r.broadcastAppendEntries()
+
+	select {
+	case res := <-result:
+		if res.err == ERR_NOT_LEADER {
+			r.mtx.Lock()
+			defer r.mtx.Unlock()
+			return nil, r.notLeaderError(ctx)
+		}
+		return res.version, res.err
+	case <-ctx.Done():
+		r.mtx.Lock()
+		delete(r.pending, index)
+		r.mtx.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (r *RaftSurfstore) GetBlockStoreMap(ctx context.Context, blockHashesIn *BlockHashes) (*BlockStoreMap, error) {
+	if err := r.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.metaStore.GetBlockStoreMap(ctx, blockHashesIn)
+}
+
+func (r *RaftSurfstore) GetBlockStoreAddrs(ctx context.Context, empty *emptypb.Empty) (*BlockStoreAddrs, error) {
+	if err := r.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.metaStore.GetBlockStoreAddrs(ctx, empty)
+}
+
+// AddBlockStore replicates the new set of BlockStores through the log like
+// an UpdateFile, the leader then migrates the blocks
+func (r *RaftSurfstore) AddBlockStore(ctx context.Context, member *BlockStoreMember) (*BlockStoreAddrs, error) {
+	return r.changeMembership(ctx, func() (*BlockStoreMembership, error) {
+		return r.metaStore.membershipWith(member)
+	})
+}
+
+func (r *RaftSurfstore) RemoveBlockStore(ctx context.Context, member *BlockStoreMember) (*BlockStoreAddrs, error) {
+	return r.changeMembership(ctx, func() (*BlockStoreMembership, error) {
+		return r.metaStore.membershipWithout(member.Addr)
+	})
+}
+
+// changeMembership proposes the membership that change builds from the
+// applied one. Only one change may be in flight, a second one would be
+// built from a membership that is about to be replaced.
+func (r *RaftSurfstore) changeMembership(ctx context.Context, change func() (*BlockStoreMembership, error)) (*BlockStoreAddrs, error) {
+	r.mtx.Lock()
+	if r.role != leader {
+		err := r.notLeaderError(ctx)
+		r.mtx.Unlock()
+		return nil, err
+	}
+	for index := r.lastApplied + 1; index < r.logEnd(); index++ {
+		if r.entryAt(index).Membership != nil {
+			r.mtx.Unlock()
+			return nil, status.Error(codes.Aborted, "Another BlockStore change is in progress")
+		}
+	}
+	membership, err := change()
+	if err != nil {
+		r.mtx.Unlock()
+		return nil, err
+	}
+	entry := &UpdateOperation{Term: r.term, Membership: membership}
+	if _, err := r.propose(ctx, entry); err != nil {
+		return nil, err
+	}
+	return &BlockStoreAddrs{BlockStoreAddrs: membershipAddrs(membership)}, nil
+}
+
+func (r *RaftSurfstore) GetChangesSince(ctx context.Context, cursor *ChangeCursor) (*FileInfoChanges, error) {
+	if err := r.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	changes, err := r.metaStore.GetChangesSince(ctx, cursor)
+	if err != nil {
+		return nil, err
+	}
+	fileInfoMap := map[string]*FileMetaData{}
+	for fileName, fileMetaData := range changes.FileInfoMap {
+		fileInfoMap[fileName] = fileMetaData
+	}
+	return &FileInfoChanges{FileInfoMap: fileInfoMap, Cursor: changes.Cursor, Full: changes.Full}, nil
+}
+
+func (r *RaftSurfstore) ListFileVersions(ctx context.Context, fileName *FileName) (*FileVersions, error) {
+	if err := r.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.metaStore.ListFileVersions(ctx, fileName)
+}
+
+func (r *RaftSurfstore) GetFileVersion(ctx context.Context, fileVersion *FileVersion) (*FileMetaData, error) {
+	if err := r.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.metaStore.GetFileVersion(ctx, fileVersion)
+}
+
+func (r *RaftSurfstore) GetReferencedBlockHashes(ctx context.Context, empty *emptypb.Empty) (*BlockHashes, error) {
+	if err := r.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.metaStore.GetReferencedBlockHashes(ctx, empty)
+}
+
+// WatchChanges streams committed changes from the leader. The stream keeps
+// running if this server loses leadership, it only ever reports committed
+// changes.
+func (r *RaftSurfstore) WatchChanges(request *WatchRequest, stream MetaStore_WatchChangesServer) error {
+	if err := r.checkLeader(stream.Context()); err != nil {
+		return err
+	}
+	return r.metaStore.WatchChanges(request, stream)
+}
+
+// checkLeader makes sure this server may serve a read: it must be the
+// leader, still be recognised by a majority of the cluster and have
+// applied everything committed before its term started.
+func (r *RaftSurfstore) checkLeader(ctx context.Context) error {
+	r.mtx.Lock()
+	if r.role != leader {
+		err := r.notLeaderError(ctx)
+		r.mtx.Unlock()
+		return err
+	}
+	r.mtx.Unlock()
+
+	if !r.broadcastAppendEntries() {
+		return status.Error(codes.Unavailable, ERR_NO_MAJORITY.Error())
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.role != leader {
+		return r.notLeaderError(ctx)
+	}
+	if r.lastApplied < r.termStartIndex {
+		return status.Error(codes.Unavailable, ERR_LEADER_NOT_READY.Error())
+	}
+	return nil
+}
+
+// notLeaderError builds the error followers return to clients, naming the
+// current leader in the response trailer. Must be called with r.mtx held.
+func (r *RaftSurfstore) notLeaderError(ctx context.Context) error {
+	if r.leaderId >= 0 {
+		// fails harmlessly when called outside of a gRPC handler
+		grpc.SetTrailer(ctx, metadata.Pairs(LEADER_ADDR_KEY, r.peers[r.leaderId]))
+	}
+	return status.Error(codes.FailedPrecondition, ERR_NOT_LEADER.Error())
+}
+
+func (r *RaftSurfstore) AppendEntries(ctx context.Context, input *AppendEntryInput) (*AppendEntryOutput, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	output := &AppendEntryOutput{ServerId: r.id, Term: r.term, Success: false, MatchedIndex: -1}
+	if input.Term < r.term {
+		return output, nil
+	}
+	if input.Term > r.term || r.role != follower {
+		r.becomeFollower(input.Term)
+	}
+	r.leaderId = input.LeaderId
+	r.resetElectionDeadline()
+	output.Term = r.term
+
+	// the leader backs up to just past MatchedIndex and retries
+	if input.PrevLogIndex >= r.logEnd() {
+		output.MatchedIndex = r.logEnd() - 1
+		return output, nil
+	}
+	// entries compacted into the snapshot are committed, they always match
+	if input.PrevLogIndex >= r.logStart() && r.termAt(input.PrevLogIndex) != input.PrevLogTerm {
+		output.MatchedIndex = input.PrevLogIndex - 1
+		return output, nil
+	}
+
+	for i, entry := range input.Entries {
+		index := input.PrevLogIndex + 1 + int64(i)
+		if index < r.logStart() {
+			continue
+		}
+		if index < r.logEnd() {
+			if r.termAt(index) == entry.Term {
+				continue
+			}
+			// conflicting entry, drop it and everything after it
+			r.log = r.log[:index-r.logStart()]
+			r.log = append(r.log, input.Entries[i:]...)
+			if r.storage != nil {
+				if err := r.storage.rewriteLog(r.log); err != nil {
+					return nil, err
+				}
+			}
+			break
+		}
+		r.log = append(r.log, input.Entries[i:]...)
+		if r.storage != nil {
+			if err := r.storage.appendEntries(input.Entries[i:]); err != nil {
+				return nil, err
+			}
+		}
+		break
+	}
+
+	lastNewIndex := input.PrevLogIndex + int64(len(input.Entries))
+	if input.LeaderCommit > r.commitIndex {
+		newCommitIndex := input.LeaderCommit
+		if lastNewIndex < newCommitIndex {
+			newCommitIndex = lastNewIndex
+		}
+		if newCommitIndex > r.commitIndex {
+			r.commitIndex = newCommitIndex
+			r.applyCommitted()
+		}
+	}
+
+	output.Success = true
+	output.MatchedIndex = lastNewIndex
+	return output, nil
+}
+
+func (r *RaftSurfstore) RequestVote(ctx context.Context, input *RequestVoteInput) (*RequestVoteOutput, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if input.Term > r.term {
+		r.becomeFollower(input.Term)
+	}
+	output := &RequestVoteOutput{Term: r.term, VoteGranted: false}
+	if input.Term < r.term {
+		return output, nil
+	}
+
+	lastLogIndex, lastLogTerm := r.lastLogIndexAndTerm()
+	upToDate := input.LastLogTerm > lastLogTerm ||
+		(input.LastLogTerm == lastLogTerm && input.LastLogIndex >= lastLogIndex)
+	if (r.votedFor == -1 || r.votedFor == input.CandidateId) && upToDate {
+		r.votedFor = input.CandidateId
+		if err := r.persistState(); err != nil {
+			return nil, err
+		}
+		r.resetElectionDeadline()
+		output.VoteGranted = true
+	}
+	return output, nil
+}
+
+// run drives elections and heartbeats until Stop is called.
+func (r *RaftSurfstore) run() {
+	ticker := time.NewTicker(RAFT_TICK_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+
+		r.mtx.Lock()
+		now := time.Now()
+		if r.role == leader {
+			sendHeartbeat := now.Sub(r.lastHeartbeat) >= RAFT_HEARTBEAT_INTERVAL
+			if sendHeartbeat {
+				r.lastHeartbeat = now
+			}
+			r.mtx.Unlock()
+			if sendHeartbeat {
+				go r.broadcastAppendEntries()
+			}
+		} else {
+			electionDue := now.After(r.electionDeadline)
+			r.mtx.Unlock()
+			if electionDue {
+				r.startElection()
+			}
+		}
+	}
+}
+
+func (r *RaftSurfstore) startElection() {
+	r.mtx.Lock()
+	r.role = candidate
+	r.term++
+	r.votedFor = r.id
+	r.leaderId = -1
+	r.resetElectionDeadline()
+	if err := r.persistState(); err != nil {
+		log.Println("RAFT: Error persisting state:", err)
+		r.mtx.Unlock()
+		return
+	}
+	term := r.term
+	lastLogIndex, lastLogTerm := r.lastLogIndexAndTerm()
+	input := &RequestVoteInput{Term: term, CandidateId: r.id, LastLogIndex: lastLogIndex, LastLogTerm: lastLogTerm}
+	log.Printf("RAFT: Server %d starting election for term %d\n", r.id, term)
+	r.mtx.Unlock()
+
+	votes := make(chan bool, len(r.peers))
+	for i := range r.peers {
+		if int64(i) == r.id {
+			continue
+		}
+		go func(i int) {
+			ctx, cancel := context.WithTimeout(context.Background(), RAFT_RPC_TIMEOUT)
+			defer cancel()
+			output, err := r.clients[i].RequestVote(ctx, input)
+			if err != nil {
+				votes <- false
+				return
+			}
+			r.mtx.Lock()
+			if output.Term > r.term {
+				r.becomeFollower(output.Term)
+			}
+			r.mtx.Unlock()
+			votes <- output.VoteGranted && output.Term == term
+		}(i)
+	}
+
+	granted := 1
+	for i := 1; i < len(r.peers) && !r.isMajority(granted); i++ {
+		if <-votes {
+			granted++
+		}
+	}
+	if !r.isMajority(granted) {
+		return
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.role == candidate && r.term == term {
+		r.becomeLeader()
+	}
+}
+
+// becomeLeader must be called with r.mtx held.
+func (r *RaftSurfstore) becomeLeader() {
+	log.Printf("RAFT: Server %d became leader for term %d\n", r.id, r.term)
+	r.role = leader
+	r.leaderId = r.id
+	for i := range r.peers {
+		r.nextIndex[i] = r.logEnd()
+		r.matchIndex[i] = -1
+	}
+	// a no-op entry from the new term lets the leader commit (and so
+	// apply) everything left over from earlier terms
+	noop := &UpdateOperation{Term: r.term, FileMetaData: nil, Index: r.logEnd()}
+	if r.storage != nil {
+		if err := r.storage.appendEntries([]*UpdateOperation{noop}); err != nil {
+			log.Println("RAFT: Error persisting log:", err)
+			r.becomeFollower(r.term)
+			return
+		}
+	}
+	r.log = append(r.log, noop)
+	r.termStartIndex = noop.Index
+	r.lastHeartbeat = time.Time{}
+	r.advanceCommitIndex()
+}
+
+// becomeFollower must be called with r.mtx held.
+func (r *RaftSurfstore) becomeFollower(term int64) {
+	if term > r.term {
+		r.term = term
+		r.votedFor = -1
+		if err := r.persistState(); err != nil {
+			log.Println("RAFT: Error persisting state:", err)
+		}
+	}
+	if r.role == leader {
+		log.Printf("RAFT: Server %d stepping down in term %d\n", r.id, r.term)
+		// whoever leads next decides what happens to these entries
+		for index, result := range r.pending {
+			result <- applyResult{err: ERR_NOT_LEADER}
+			delete(r.pending, index)
+		}
+	}
+	r.role = follower
+	r.leaderId = -1
+}
+
+// broadcastAppendEntries sends AppendEntries (heartbeats if there is
+// nothing new) to every peer. It reports whether a majority of the cluster
+// acknowledged this server as leader.
+func (r *RaftSurfstore) broadcastAppendEntries() bool {
+	acks := make(chan bool, len(r.peers))
+	for i := range r.peers {
+		if int64(i) == r.id {
+			continue
+		}
+		go func(i int) {
+			acks <- r.replicateTo(i)
+		}(i)
+	}
+
+	acked := 1
+	for i := 1; i < len(r.peers) && !r.isMajority(acked); i++ {
+		if <-acks {
+			acked++
+		}
+	}
+	return r.isMajority(acked)
+}
+
+// replicateTo sends peer i every entry it is missing and reports whether
+// it accepted this server as leader.
+func (r *RaftSurfstore) replicateTo(i int) bool {
+	r.mtx.Lock()
+	if r.role != leader {
+		r.mtx.Unlock()
+		return false
+	}
+	term := r.term
+	if r.nextIndex[i] < r.logStart() {
+		// the entries it is missing were compacted away
+		snapshot := r.snapshot
+		r.mtx.Unlock()
+		return r.sendSnapshot(i, term, snapshot)
+	}
+	prevLogIndex := r.nextIndex[i] - 1
+	prevLogTerm := r.termAt(prevLogIndex)
+	entries := append([]*UpdateOperation{}, r.log[prevLogIndex+1-r.logStart():]...)
+	input := &AppendEntryInput{
+		Term:         term,
+		LeaderId:     r.id,
+		PrevLogTerm:  prevLogTerm,
+		PrevLogIndex: prevLogIndex,
+		Entries:      entries,
+		LeaderCommit: r.commitIndex,
+	}
+	r.mtx.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), RAFT_RPC_TIMEOUT)
+	defer cancel()
+	output, err := r.clients[i].AppendEntries(ctx, input)
+	if err != nil {
+		return false
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if output.Term > r.term {
+		r.becomeFollower(output.Term)
+		return false
+	}
+	if r.role != leader || r.term != term {
+		return false
+	}
+	if output.Success {
+		r.matched(i, prevLogIndex+int64(len(entries)))
+	} else if r.nextIndex[i] == prevLogIndex+1 {
+		nextIndex := output.MatchedIndex + 1
+		if nextIndex > prevLogIndex {
+			nextIndex = prevLogIndex
+		}
+		if nextIndex < 0 {
+			nextIndex = 0
+		}
+		r.nextIndex[i] = nextIndex
+	}
+	return true
+}
+
+// sendSnapshot installs snapshot on peer i and reports whether it accepted
+// this server as leader.
+func (r *RaftSurfstore) sendSnapshot(i int, term int64, snapshot *RaftSnapshot) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), RAFT_SNAPSHOT_TIMEOUT)
+	defer cancel()
+	output, err := r.clients[i].InstallSnapshot(ctx, &InstallSnapshotInput{Term: term, LeaderId: r.id, Snapshot: snapshot})
+	if err != nil {
+		return false
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if output.Term > r.term {
+		r.becomeFollower(output.Term)
+		return false
+	}
+	if r.role != leader || r.term != term {
+		return false
+	}
+	if output.Success {
+		r.matched(i, snapshot.LastIndex)
+	}
+	return true
+}
+
+// matched records that peer i stores the log up to matchIndex. Must be
+// called with r.mtx held.
+func (r *RaftSurfstore) matched(i int, matchIndex int64) {
+	if matchIndex > r.matchIndex[i] {
+		r.matchIndex[i] = matchIndex
+	}
+	if matchIndex+1 > r.nextIndex[i] {
+		r.nextIndex[i] = matchIndex + 1
+	}
+	r.advanceCommitIndex()
+}
+
+// advanceCommitIndex commits the newest entry of the current term that a
+// majority has stored. Must be called with r.mtx held.
+func (r *RaftSurfstore) advanceCommitIndex() {
+	for index := r.logEnd() - 1; index > r.commitIndex; index-- {
+		// entries from older terms are only committed indirectly
+		if r.termAt(index) != r.term {
+			return
+		}
+		stored := 1
+		for i := range r.peers {
+			if int64(i) != r.id && r.matchIndex[i] >= index {
+				stored++
+			}
+		}
+		if r.isMajority(stored) {
+			r.commitIndex = index
+			r.applyCommitted()
+			return
+		}
+	}
+}
+
+// applyCommitted applies committed entries to the MetaStore in log order
+// and hands the results to waiting UpdateFile calls. Must be called with
+// r.mtx held.
+func (r *RaftSurfstore) applyCommitted() {
+	for r.lastApplied < r.commitIndex {
+		r.lastApplied++
+		entry := r.entryAt(r.lastApplied)
+		var version *Version
+		var err error
+		if entry.Membership != nil {
+			r.metaStore.setMembership(entry.Membership)
+		} else if entry.FileMetaData != nil {
+			version, err = r.metaStore.UpdateFile(context.Background(), entry.FileMetaData)
+		} else {
+			continue
+		}
+		if result, ok := r.pending[r.lastApplied]; ok {
+			result <- applyResult{version: version, err: err}
+			delete(r.pending, r.lastApplied)
+		}
+	}
+	r.compactLog()
+	if r.role == leader {
+		// also picks up a migration the previous leader did not finish
+		r.metaStore.migrator.start()
+	}
+}
+
+// compactLog snapshots the MetaStore and drops the applied entries from the
+// log once SNAPSHOT_INTERVAL of them have piled up. Must be called with
+// r.mtx held.
+func (r *RaftSurfstore) compactLog() {
+	if r.lastApplied-r.logStart()+1 < int64(SNAPSHOT_INTERVAL) {
+		return
+	}
+	r.metaStore.mtx.RLock()
+	state := proto.Clone(r.metaStore.snapshot()).(*MetaSnapshot)
+	r.metaStore.mtx.RUnlock()
+	snapshot := &RaftSnapshot{LastIndex: r.lastApplied, LastTerm: r.termAt(r.lastApplied), State: state}
+	entries := append([]*UpdateOperation{}, r.log[r.lastApplied+1-r.logStart():]...)
+	if r.storage != nil {
+		if err := r.storage.saveSnapshot(snapshot, entries); err != nil {
+			// nothing is lost, the log only keeps growing
+			log.Println("RAFT: Error compacting log:", err)
+			return
+		}
+	}
+	r.snapshot = snapshot
+	r.log = entries
+}
+
+// InstallSnapshot replaces the state of a follower that is missing entries
+// the leader has already compacted with the leader's snapshot
+func (r *RaftSurfstore) InstallSnapshot(ctx context.Context, input *InstallSnapshotInput) (*AppendEntryOutput, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	output := &AppendEntryOutput{ServerId: r.id, Term: r.term, Success: false, MatchedIndex: -1}
+	if input.Term < r.term {
+		return output, nil
+	}
+	if input.Term > r.term || r.role != follower {
+		r.becomeFollower(input.Term)
+	}
+	r.leaderId = input.LeaderId
+	r.resetElectionDeadline()
+	output.Term = r.term
+
+	snapshot := input.Snapshot
+	if snapshot.LastIndex > r.lastApplied {
+		// entries after the snapshot are kept if the log agrees with it
+		entries := []*UpdateOperation{}
+		if snapshot.LastIndex >= r.logStart() && snapshot.LastIndex < r.logEnd() && r.termAt(snapshot.LastIndex) == snapshot.LastTerm {
+			entries = append(entries, r.log[snapshot.LastIndex+1-r.logStart():]...)
+		}
+		if r.storage != nil {
+			if err := r.storage.saveSnapshot(snapshot, entries); err != nil {
+				return nil, err
+			}
+		}
+		r.metaStore.mtx.Lock()
+		r.metaStore.restore(snapshot.State)
+		r.metaStore.mtx.Unlock()
+		r.snapshot = snapshot
+		r.log = entries
+		r.lastApplied = snapshot.LastIndex
+		if r.commitIndex < snapshot.LastIndex {
+			r.commitIndex = snapshot.LastIndex
+		}
+	}
+	output.Success = true
+	output.MatchedIndex = snapshot.LastIndex
+	return output, nil
+}
+
+// isLeader reports whether this server is currently the leader
+func (r *RaftSurfstore) isLeader() bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.role == leader
+}
+
+func (r *RaftSurfstore) lastLogIndexAndTerm() (int64, int64) {
+	return r.logEnd() - 1, r.termAt(r.logEnd() - 1)
+}
+
+// logStart is the index of the first entry in r.log, the entries before it
+// were compacted into r.snapshot
+func (r *RaftSurfstore) logStart() int64 {
+	if r.snapshot == nil {
+		return 0
+	}
+	return r.snapshot.LastIndex + 1
+}
+
+// logEnd is the index the next entry is appended at
+func (r *RaftSurfstore) logEnd() int64 {
+	return r.logStart() + int64(len(r.log))
+}
+
+func (r *RaftSurfstore) entryAt(index int64) *UpdateOperation {
+	return r.log[index-r.logStart()]
+}
+
+// termAt returns the term of the entry at index, which may also be the last
+// entry compacted into the snapshot, or -1 before the first entry
+func (r *RaftSurfstore) termAt(index int64) int64 {
+	if index < 0 {
+		return -1
+	}
+	if index < r.logStart() {
+		return r.snapshot.LastTerm
+	}
+	return r.entryAt(index).Term
+}
+
+func (r *RaftSurfstore) isMajority(count int) bool {
+	return count > len(r.peers)/2
+}
+
+func (r *RaftSurfstore) resetElectionDeadline() {
+	timeout := RAFT_ELECTION_TIMEOUT + time.Duration(rand.Int63n(int64(RAFT_ELECTION_TIMEOUT)))
+	r.electionDeadline = time.Now().Add(timeout)
+}
+
+func (r *RaftSurfstore) persistState() error {
+	if r.storage == nil {
+		return nil
+	}
+	return r.storage.saveState(r.term, r.votedFor)
+}
+
+// SetConsistentHashRing replaces the ring blocks are mapped to BlockStores
+// with. Every replica must be given the same ring.
+func (r *RaftSurfstore) SetConsistentHashRing(ring *ConsistentHashRing) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.metaStore.SetConsistentHashRing(ring)
+}
+
+// Stop halts elections and heartbeats and closes the connections to the
+// other replicas.
+func (r *RaftSurfstore) Stop() {
+	close(r.stop)
+	for _, conn := range r.conns {
+		if conn != nil {
+			conn.Close()
+		}
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.storage != nil {
+		r.storage.close()
+	}
+}
+
+// This line guarantees all method for RaftSurfstore are implemented
+var _ RaftInterface = new(RaftSurfstore)
+
+// Create the replica with index id of a Raft cluster made up of the
+// MetaStores at peers, keeping historySize previous versions of each file.
+// If raftDir is not empty the replica's term, vote and log are persisted
+// there. The replica starts taking part in elections straight away.
+func NewRaftServer(id int64, peers []string, blockStoreAddrs []string, raftDir string, historySize int) (*RaftSurfstore, error) {
+	metaStore, err := NewMetaStore(blockStoreAddrs, "", historySize)
+	if err != nil {
+		return nil, err
+	}
+	server := &RaftSurfstore{
+		id:          id,
+		peers:       peers,
+		metaStore:   metaStore,
+		clients:     make([]RaftSurfstoreClient, len(peers)),
+		conns:       make([]*grpc.ClientConn, len(peers)),
+		stop:        make(chan struct{}),
+		role:        follower,
+		term:        0,
+		votedFor:    -1,
+		leaderId:    -1,
+		log:         []*UpdateOperation{},
+		commitIndex: -1,
+		lastApplied: -1,
+		nextIndex:   make([]int64, len(peers)),
+		matchIndex:  make([]int64, len(peers)),
+		pending:     map[int64]chan applyResult{},
+	}
+	metaStore.migrator.active = server.isLeader
+	if raftDir != "" {
+		storage, err := openRaftStorage(raftDir)
+		if err != nil {
+			return nil, err
+		}
+		state, snapshot, entries, err := storage.load()
+		if err != nil {
+			storage.close()
+			return nil, err
+		}
+		server.storage = storage
+		server.term = state.Term
+		server.votedFor = state.VotedFor
+		server.log = entries
+		if snapshot != nil {
+			metaStore.restore(snapshot.State)
+			server.snapshot = snapshot
+			server.commitIndex = snapshot.LastIndex
+			server.lastApplied = snapshot.LastIndex
+		}
+	}
+	for i, peer := range peers {
+		if int64(i) == id {
+			continue
+		}
+		// Dial does not block, peers that are down are retried on each call
+		conn, err := grpc.Dial(peer, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			server.Stop()
+			return nil, err
+		}
+		server.conns[i] = conn
+		server.clients[i] = NewRaftSurfstoreClient(conn)
+	}
+	server.resetElectionDeadline()
+	go server.run()
+	return server, nil
+}
@@ -0,0 +1,328 @@
+package surfstore
+
+import (
+	context "context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// raftTestNode is one replica of an in-process Raft cluster
+type raftTestNode struct {
+	id     int
+	peers  []string
+	dir    string
+	raft   *RaftSurfstore
+	server *grpc.Server
+}
+
+// startRaftCluster serves n replicas on free local ports. Persistent
+// replicas keep their state in a temp directory and can be restarted.
+func startRaftCluster(t *testing.T, n int, persistent bool) []*raftTestNode {
+	t.Helper()
+	listeners := make([]net.Listener, n)
+	peers := make([]string, n)
+	for i := range listeners {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		listeners[i] = l
+		peers[i] = l.Addr().String()
+	}
+	nodes := make([]*raftTestNode, n)
+	for i := range nodes {
+		nodes[i] = &raftTestNode{id: i, peers: peers}
+		if persistent {
+			nodes[i].dir = t.TempDir()
+		}
+		nodes[i].serve(t, listeners[i])
+	}
+	t.Cleanup(func() {
+		for _, node := range nodes {
+			node.stop()
+		}
+	})
+	return nodes
+}
+
+func (node *raftTestNode) serve(t *testing.T, l net.Listener) {
+	t.Helper()
+	raft, err := NewRaftServer(int64(node.id), node.peers, nil, node.dir, DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	RegisterMetaStoreServer(server, raft)
+	RegisterRaftSurfstoreServer(server, raft)
+	go server.Serve(l)
+	node.raft = raft
+	node.server = server
+}
+
+func (node *raftTestNode) addr() string {
+	return node.peers[node.id]
+}
+
+// stop kills the replica, a persistent one keeps what it stored on disk
+func (node *raftTestNode) stop() {
+	if node.server == nil {
+		return
+	}
+	node.server.Stop()
+	node.raft.Stop()
+	node.server = nil
+}
+
+func (node *raftTestNode) restart(t *testing.T) {
+	t.Helper()
+	l, err := net.Listen("tcp", node.addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.serve(t, l)
+}
+
+// version returns the version of fileName this replica has applied, 0 if
+// it has none
+func (node *raftTestNode) version(fileName string) int32 {
+	node.raft.mtx.Lock()
+	defer node.raft.mtx.Unlock()
+	if fileMetaData, ok := node.raft.metaStore.FileMetaMap[fileName]; ok {
+		return fileMetaData.Version
+	}
+	return 0
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForLeader waits until exactly one running replica leads the cluster
+func waitForLeader(t *testing.T, nodes []*raftTestNode) *raftTestNode {
+	t.Helper()
+	var leader *raftTestNode
+	waitFor(t, "a leader is elected", func() bool {
+		leader = nil
+		leaders := 0
+		for _, node := range nodes {
+			if node.server != nil && node.raft.isLeader() {
+				leader = node
+				leaders++
+			}
+		}
+		return leaders == 1
+	})
+	return leader
+}
+
+func raftTestClient(nodes []*raftTestNode) *RPCClient {
+	return NewSurfstoreRPCClient(strings.Join(nodes[0].peers, CONFIG_DELIMITER), "", 0)
+}
+
+func updateVersion(ctx context.Context, client *RPCClient, fileName string, version int32) error {
+	var latestVersion int32
+	return client.UpdateFile(ctx, &FileMetaData{Filename: fileName, Version: version, BlockHashList: []string{"h"}}, &latestVersion)
+}
+
+func TestRaftElectsLeader(t *testing.T) {
+	nodes := startRaftCluster(t, 3, false)
+	leader := waitForLeader(t, nodes)
+	waitFor(t, "every follower follows the leader", func() bool {
+		for _, node := range nodes {
+			node.raft.mtx.Lock()
+			leaderId, term := node.raft.leaderId, node.raft.term
+			node.raft.mtx.Unlock()
+			leader.raft.mtx.Lock()
+			leaderTerm := leader.raft.term
+			leader.raft.mtx.Unlock()
+			if leaderId != int64(leader.id) || term != leaderTerm {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// An update commits once a majority has stored it, and not before
+func TestRaftReplicatesToMajority(t *testing.T) {
+	nodes := startRaftCluster(t, 3, false)
+	leader := waitForLeader(t, nodes)
+	followers := []*raftTestNode{}
+	for _, node := range nodes {
+		if node != leader {
+			followers = append(followers, node)
+		}
+	}
+	client := raftTestClient(nodes)
+	defer client.Close()
+
+	followers[0].stop()
+	if err := updateVersion(context.Background(), client, "f.txt", 1); err != nil {
+		t.Fatalf("update with a majority up: %v", err)
+	}
+	waitFor(t, "the live follower applies the update", func() bool {
+		return followers[1].version("f.txt") == 1
+	})
+	if leader.version("f.txt") != 1 {
+		t.Fatal("the leader did not apply the committed update")
+	}
+
+	followers[1].stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := updateVersion(ctx, client, "f.txt", 2); err == nil {
+		t.Fatal("an update committed with only the leader up")
+	}
+	if got := leader.version("f.txt"); got != 1 {
+		t.Fatalf("the leader applied version %d without a majority", got)
+	}
+}
+
+// Followers refuse MetaStore calls and point the client at the leader
+func TestRaftFollowerRejects(t *testing.T) {
+	nodes := startRaftCluster(t, 3, false)
+	leader := waitForLeader(t, nodes)
+	follower := nodes[(leader.id+1)%len(nodes)]
+	conn, err := grpc.Dial(follower.addr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := NewMetaStoreClient(conn)
+
+	calls := map[string]func(trailer *metadata.MD) error{
+		"GetFileInfoMap": func(trailer *metadata.MD) error {
+			_, err := c.GetFileInfoMap(context.Background(), &emptypb.Empty{}, grpc.Trailer(trailer))
+			return err
+		},
+		"UpdateFile": func(trailer *metadata.MD) error {
+			_, err := c.UpdateFile(context.Background(), &FileMetaData{Filename: "f.txt", Version: 1, BlockHashList: []string{"h"}}, grpc.Trailer(trailer))
+			return err
+		},
+	}
+	for name, call := range calls {
+		var trailer metadata.MD
+		waitFor(t, "the follower knows the leader", func() bool {
+			trailer = metadata.MD{}
+			err = call(&trailer)
+			return len(trailer.Get(LEADER_ADDR_KEY)) > 0
+		})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("%s on a follower: got %v, want FailedPrecondition", name, err)
+		}
+		if got := trailer.Get(LEADER_ADDR_KEY)[0]; got != leader.addr() {
+			t.Fatalf("%s on a follower names %s as leader, want %s", name, got, leader.addr())
+		}
+	}
+	if follower.version("f.txt") != 0 || leader.version("f.txt") != 0 {
+		t.Fatal("an update sent to a follower was applied")
+	}
+}
+
+// A committed update survives the leader being killed, and the old leader
+// catches up when it comes back
+func TestRaftSurvivesLeaderFailure(t *testing.T) {
+	nodes := startRaftCluster(t, 3, true)
+	leader := waitForLeader(t, nodes)
+	client := raftTestClient(nodes)
+	defer client.Close()
+	ctx := context.Background()
+	if err := updateVersion(ctx, client, "f.txt", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	leader.stop()
+	newLeader := waitForLeader(t, nodes)
+	if newLeader == leader {
+		t.Fatal("a stopped replica is still leading")
+	}
+	var fileInfoMap map[string]*FileMetaData
+	if err := client.GetFileInfoMap(ctx, &fileInfoMap); err != nil {
+		t.Fatal(err)
+	}
+	if fileInfoMap["f.txt"] == nil || fileInfoMap["f.txt"].Version != 1 {
+		t.Fatalf("committed update lost with the leader, got %v", fileInfoMap["f.txt"])
+	}
+	if err := updateVersion(ctx, client, "f.txt", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	leader.restart(t)
+	waitFor(t, "the old leader catches up", func() bool {
+		return leader.version("f.txt") == 2
+	})
+}
+
+// The log is compacted into a snapshot, a replica that missed the
+// compacted entries is sent the snapshot, and every replica restarts from
+// its own snapshot and log
+func TestRaftCompactsLog(t *testing.T) {
+	nodes := startRaftCluster(t, 3, true)
+	leader := waitForLeader(t, nodes)
+	lagging := nodes[(leader.id+1)%len(nodes)]
+	lagging.stop()
+	client := raftTestClient(nodes)
+	defer client.Close()
+	ctx := context.Background()
+
+	updates := int32(SNAPSHOT_INTERVAL + 10)
+	for version := int32(1); version <= updates; version++ {
+		if err := updateVersion(ctx, client, "f.txt", version); err != nil {
+			t.Fatal(err)
+		}
+	}
+	leader.raft.mtx.Lock()
+	snapshot, logLength := leader.raft.snapshot, len(leader.raft.log)
+	leader.raft.mtx.Unlock()
+	if snapshot == nil || logLength >= SNAPSHOT_INTERVAL {
+		t.Fatalf("log of %d entries was not compacted", logLength)
+	}
+	if _, err := os.Stat(filepath.Join(leader.dir, RAFT_SNAPSHOT_FILENAME)); err != nil {
+		t.Fatalf("snapshot not persisted: %v", err)
+	}
+
+	lagging.restart(t)
+	waitFor(t, "the lagging replica installs the snapshot", func() bool {
+		return lagging.version("f.txt") == updates
+	})
+
+	for _, node := range nodes {
+		node.stop()
+	}
+	for _, node := range nodes {
+		node.restart(t)
+		// nothing is committed before an election, this is the snapshot
+		if node.version("f.txt") == 0 {
+			t.Fatalf("replica %d did not restore its snapshot", node.id)
+		}
+	}
+	waitFor(t, "every replica replays its log", func() bool {
+		for _, node := range nodes {
+			if node.version("f.txt") != updates {
+				return false
+			}
+		}
+		return true
+	})
+	if err := updateVersion(ctx, client, "f.txt", updates+1); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,121 @@
+package surfstore
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const createBlockTable string = `CREATE TABLE IF NOT EXISTS blocks(
+	hash TEXT PRIMARY KEY,
+	data BLOB,
+	storedAt INT
+);`
+
+const insertBlock string = `insert or ignore into blocks(hash, data, storedAt) VALUES (?, ?, ?);`
+
+const getBlock string = `select data from blocks where hash = ?;`
+
+const getBlockInfo string = `select ifnull(length(data), 0), storedAt from blocks where hash = ?;`
+
+const getBlockHashes string = `select hash from blocks;`
+
+const deleteBlock string = `delete from blocks where hash = ?;`
+
+const touchBlock string = `update blocks set storedAt = ? where hash = ?;`
+
+// SQLiteBlockStorage persists blocks in the SQLite database
+// BLOCK_DB_FILENAME inside a directory
+type SQLiteBlockStorage struct {
+	db *sql.DB
+}
+
+func (s *SQLiteBlockStorage) Get(hash string) (*Block, error) {
+	var blockData []byte
+	err := s.db.QueryRow(getBlock, hash).Scan(&blockData)
+	if err == sql.ErrNoRows {
+		return nil, ERR_BLOCK_NOT_FOUND
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Block{BlockData: blockData, BlockSize: int32(len(blockData))}, nil
+}
+
+func (s *SQLiteBlockStorage) Put(hash string, block *Block) error {
+	_, err := s.db.Exec(insertBlock, hash, block.BlockData, time.Now().UnixNano())
+	return err
+}
+
+func (s *SQLiteBlockStorage) Has(hash string) (bool, error) {
+	_, err := s.Stat(hash)
+	if err == ERR_BLOCK_NOT_FOUND {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *SQLiteBlockStorage) List() ([]string, error) {
+	rows, err := s.db.Query(getBlockHashes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hashes := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+func (s *SQLiteBlockStorage) Delete(hash string) error {
+	_, err := s.db.Exec(deleteBlock, hash)
+	return err
+}
+
+func (s *SQLiteBlockStorage) Stat(hash string) (*BlockInfo, error) {
+	var size int64
+	var storedAt int64
+	err := s.db.QueryRow(getBlockInfo, hash).Scan(&size, &storedAt)
+	if err == sql.ErrNoRows {
+		return nil, ERR_BLOCK_NOT_FOUND
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &BlockInfo{Hash: hash, Size: size, StoredAt: time.Unix(0, storedAt)}, nil
+}
+
+func (s *SQLiteBlockStorage) Touch(hash string) error {
+	_, err := s.db.Exec(touchBlock, time.Now().UnixNano(), hash)
+	return err
+}
+
+// This line guarantees all method for SQLiteBlockStorage are implemented
+var _ BlockStorage = new(SQLiteBlockStorage)
+
+// Create a SQLiteBlockStorage backed by BLOCK_DB_FILENAME in blockDir
+func NewSQLiteBlockStorage(blockDir string) (*SQLiteBlockStorage, error) {
+	if err := os.MkdirAll(blockDir, 0755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(blockDir, BLOCK_DB_FILENAME))
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer, one connection queues concurrent
+	// calls instead of failing them with "database is locked"
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(createBlockTable); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteBlockStorage{db: db}, nil
+}
@@ -1,7 +1,20 @@
 package surfstore
 
+import "time"
+
 const DEFAULT_META_FILENAME string = "index.db"
 
+// Files the client keeps in the base directory for itself start with this
+// prefix and are never synced
+const CLIENT_FILE_PREFIX string = ".surfstore-"
+
+// Holds the change cursor of the last sync, next to index.db
+const CURSOR_FILENAME string = CLIENT_FILE_PREFIX + "cursor"
+
+// Downloads are written to a temp file with this prefix in the directory
+// of the file before they are renamed into place
+const DOWNLOAD_FILE_PREFIX string = CLIENT_FILE_PREFIX + "download-"
+
 const TOMBSTONE_HASHVALUE string = "0"
 const EMPTYFILE_HASHVALUE string = "-1"
 
@@ -11,3 +24,77 @@ const HASH_LIST_INDEX int = 2
 
 const CONFIG_DELIMITER string = ","
 const HASH_DELIMITER string = " "
+
+// Largest block that fits in one gRPC message under the default 4 MB
+// limit, with room left for the rest of the message
+const MAX_BLOCK_SIZE int = 4<<20 - 1<<10
+
+const BLOCK_DB_FILENAME string = "blocks.db"
+
+const META_LOG_FILENAME string = "meta.wal"
+const META_SNAPSHOT_FILENAME string = "meta.snapshot"
+
+// Number of log entries after which the MetaStore snapshots its state and
+// truncates the log, Raft replicas compact their logs just as often
+const SNAPSHOT_INTERVAL int = 1000
+
+// Largest log record replay accepts, far above any update gRPC lets
+// through. A longer length in a record header is treated as a torn tail.
+const MAX_LOG_RECORD_SIZE uint32 = 64 << 20
+
+// Number of previous versions of each file the MetaStore keeps by default
+const DEFAULT_FILE_HISTORY_SIZE int = 10
+
+// Garbage collection keeps unreferenced blocks stored more recently than
+// this, they may belong to a sync that has not called UpdateFile yet
+const DEFAULT_GC_GRACE_PERIOD time.Duration = time.Hour
+
+// Number of recent changes the MetaStore keeps for WatchChanges
+const CHANGE_HISTORY_SIZE int = 10000
+
+const RAFT_STATE_FILENAME string = "raft.state"
+const RAFT_LOG_FILENAME string = "raft.log"
+const RAFT_SNAPSHOT_FILENAME string = "raft.snapshot"
+
+// Raft timing: leaders send heartbeats every RAFT_HEARTBEAT_INTERVAL and
+// followers start an election after a random timeout between
+// RAFT_ELECTION_TIMEOUT and twice that without hearing from a leader
+const RAFT_TICK_INTERVAL time.Duration = 10 * time.Millisecond
+const RAFT_HEARTBEAT_INTERVAL time.Duration = 50 * time.Millisecond
+const RAFT_ELECTION_TIMEOUT time.Duration = 300 * time.Millisecond
+const RAFT_RPC_TIMEOUT time.Duration = 200 * time.Millisecond
+
+// How long a leader gives a follower to install its snapshot
+const RAFT_SNAPSHOT_TIMEOUT time.Duration = 5 * time.Second
+
+// How long a client waits for a BlockStore to send a block before trying
+// the next replica
+const DEFAULT_GET_BLOCK_TIMEOUT time.Duration = time.Second
+
+// A sync moves blocks in batches of up to BLOCK_TRANSFER_BATCH_SIZE per
+// stream, running DEFAULT_SYNC_PARALLELISM streams at once by default
+const BLOCK_TRANSFER_BATCH_SIZE int = 64
+const DEFAULT_SYNC_PARALLELISM int = 8
+
+// How many times the client cycles through the MetaStore replicas looking
+// for the leader before giving up
+const METASTORE_RETRIES int = 10
+const METASTORE_RETRY_BACKOFF time.Duration = 200 * time.Millisecond
+
+// Trailer key followers use to tell clients where the leader is
+const LEADER_ADDR_KEY string = "surfstore-leader"
+
+// Watch mode: how often the base directory is scanned for changes, how long
+// it has to stay unchanged before it is synced, and how often remote changes
+// are pulled when nothing changes locally
+const DEFAULT_WATCH_POLL_INTERVAL time.Duration = time.Second
+const DEFAULT_WATCH_DEBOUNCE time.Duration = 2 * time.Second
+const DEFAULT_WATCH_PULL_INTERVAL time.Duration = 30 * time.Second
+
+// Block migration after BlockStores are added or removed: blocks are copied
+// BLOCK_MIGRATION_BATCH_SIZE at a time, deleted from the BlockStores they
+// moved away from no sooner than BLOCK_MIGRATION_DELETE_DELAY after the
+// change, and a failed pass is retried after BLOCK_MIGRATION_RETRY_INTERVAL
+const BLOCK_MIGRATION_BATCH_SIZE int = 256
+const BLOCK_MIGRATION_DELETE_DELAY time.Duration = 30 * time.Second
+const BLOCK_MIGRATION_RETRY_INTERVAL time.Duration = 5 * time.Second
@@ -27,6 +27,9 @@ type BlockStoreClient interface {
 	PutBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*Success, error)
 	MissingBlocks(ctx context.Context, in *BlockHashes, opts ...grpc.CallOption) (*BlockHashes, error)
 	GetBlockHashes(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*BlockHashes, error)
+	PutBlocks(ctx context.Context, opts ...grpc.CallOption) (BlockStore_PutBlocksClient, error)
+	GetBlocks(ctx context.Context, in *BlockHashes, opts ...grpc.CallOption) (BlockStore_GetBlocksClient, error)
+	DeleteBlocks(ctx context.Context, in *DeleteBlocksRequest, opts ...grpc.CallOption) (*BlockHashes, error)
 }
 
 type blockStoreClient struct {
@@ -73,6 +76,81 @@ func (c *blockStoreClient) GetBlockHashes(ctx context.Context, in *emptypb.Empty
 	return out, nil
 }
 
+func (c *blockStoreClient) PutBlocks(ctx context.Context, opts ...grpc.CallOption) (BlockStore_PutBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &BlockStore_ServiceDesc.Streams[0], "/surfstore.BlockStore/PutBlocks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &blockStorePutBlocksClient{stream}
+	return x, nil
+}
+
+type BlockStore_PutBlocksClient interface {
+	Send(*Block) error
+	CloseAndRecv() (*Success, error)
+	grpc.ClientStream
+}
+
+type blockStorePutBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *blockStorePutBlocksClient) Send(m *Block) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *blockStorePutBlocksClient) CloseAndRecv() (*Success, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Success)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *blockStoreClient) GetBlocks(ctx context.Context, in *BlockHashes, opts ...grpc.CallOption) (BlockStore_GetBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &BlockStore_ServiceDesc.Streams[1], "/surfstore.BlockStore/GetBlocks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &blockStoreGetBlocksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type BlockStore_GetBlocksClient interface {
+	Recv() (*Block, error)
+	grpc.ClientStream
+}
+
+type blockStoreGetBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *blockStoreGetBlocksClient) Recv() (*Block, error) {
+	m := new(Block)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *blockStoreClient) DeleteBlocks(ctx context.Context, in *DeleteBlocksRequest, opts ...grpc.CallOption) (*BlockHashes, error) {
+	out := new(BlockHashes)
+	err := c.cc.Invoke(ctx, "/surfstore.BlockStore/DeleteBlocks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BlockStoreServer is the server API for BlockStore service.
 // All implementations must embed UnimplementedBlockStoreServer
 // for forward compatibility
@@ -81,6 +159,9 @@ type BlockStoreServer interface {
 	PutBlock(context.Context, *Block) (*Success, error)
 	MissingBlocks(context.Context, *BlockHashes) (*BlockHashes, error)
 	GetBlockHashes(context.Context, *emptypb.Empty) (*BlockHashes, error)
+	PutBlocks(BlockStore_PutBlocksServer) error
+	GetBlocks(*BlockHashes, BlockStore_GetBlocksServer) error
+	DeleteBlocks(context.Context, *DeleteBlocksRequest) (*BlockHashes, error)
 	mustEmbedUnimplementedBlockStoreServer()
 }
 
@@ -100,6 +181,15 @@ func (UnimplementedBlockStoreServer) MissingBlocks(context.Context, *BlockHashes
 func (UnimplementedBlockStoreServer) GetBlockHashes(context.Context, *emptypb.Empty) (*BlockHashes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetBlockHashes not implemented")
 }
+func (UnimplementedBlockStoreServer) PutBlocks(BlockStore_PutBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method PutBlocks not implemented")
+}
+func (UnimplementedBlockStoreServer) GetBlocks(*BlockHashes, BlockStore_GetBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetBlocks not implemented")
+}
+func (UnimplementedBlockStoreServer) DeleteBlocks(context.Context, *DeleteBlocksRequest) (*BlockHashes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteBlocks not implemented")
+}
 func (UnimplementedBlockStoreServer) mustEmbedUnimplementedBlockStoreServer() {}
 
 // UnsafeBlockStoreServer may be embedded to opt out of forward compatibility for this service.
@@ -185,6 +275,71 @@ func _BlockStore_GetBlockHashes_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BlockStore_PutBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BlockStoreServer).PutBlocks(&blockStorePutBlocksServer{stream})
+}
+
+type BlockStore_PutBlocksServer interface {
+	SendAndClose(*Success) error
+	Recv() (*Block, error)
+	grpc.ServerStream
+}
+
+type blockStorePutBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *blockStorePutBlocksServer) SendAndClose(m *Success) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *blockStorePutBlocksServer) Recv() (*Block, error) {
+	m := new(Block)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _BlockStore_GetBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlockHashes)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlockStoreServer).GetBlocks(m, &blockStoreGetBlocksServer{stream})
+}
+
+type BlockStore_GetBlocksServer interface {
+	Send(*Block) error
+	grpc.ServerStream
+}
+
+type blockStoreGetBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *blockStoreGetBlocksServer) Send(m *Block) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _BlockStore_DeleteBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteBlocksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockStoreServer).DeleteBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/surfstore.BlockStore/DeleteBlocks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockStoreServer).DeleteBlocks(ctx, req.(*DeleteBlocksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BlockStore_ServiceDesc is the grpc.ServiceDesc for BlockStore service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -208,8 +363,23 @@ var BlockStore_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetBlockHashes",
 			Handler:    _BlockStore_GetBlockHashes_Handler,
 		},
+		{
+			MethodName: "DeleteBlocks",
+			Handler:    _BlockStore_DeleteBlocks_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PutBlocks",
+			Handler:       _BlockStore_PutBlocks_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "GetBlocks",
+			Handler:       _BlockStore_GetBlocks_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/surfstore/SurfStore.proto",
 }
 
@@ -221,6 +391,13 @@ type MetaStoreClient interface {
 	UpdateFile(ctx context.Context, in *FileMetaData, opts ...grpc.CallOption) (*Version, error)
 	GetBlockStoreMap(ctx context.Context, in *BlockHashes, opts ...grpc.CallOption) (*BlockStoreMap, error)
 	GetBlockStoreAddrs(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*BlockStoreAddrs, error)
+	WatchChanges(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (MetaStore_WatchChangesClient, error)
+	GetChangesSince(ctx context.Context, in *ChangeCursor, opts ...grpc.CallOption) (*FileInfoChanges, error)
+	ListFileVersions(ctx context.Context, in *FileName, opts ...grpc.CallOption) (*FileVersions, error)
+	GetFileVersion(ctx context.Context, in *FileVersion, opts ...grpc.CallOption) (*FileMetaData, error)
+	GetReferencedBlockHashes(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*BlockHashes, error)
+	AddBlockStore(ctx context.Context, in *BlockStoreMember, opts ...grpc.CallOption) (*BlockStoreAddrs, error)
+	RemoveBlockStore(ctx context.Context, in *BlockStoreMember, opts ...grpc.CallOption) (*BlockStoreAddrs, error)
 }
 
 type metaStoreClient struct {
@@ -267,6 +444,92 @@ func (c *metaStoreClient) GetBlockStoreAddrs(ctx context.Context, in *emptypb.Em
 	return out, nil
 }
 
+func (c *metaStoreClient) WatchChanges(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (MetaStore_WatchChangesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MetaStore_ServiceDesc.Streams[0], "/surfstore.MetaStore/WatchChanges", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &metaStoreWatchChangesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MetaStore_WatchChangesClient interface {
+	Recv() (*FileChange, error)
+	grpc.ClientStream
+}
+
+type metaStoreWatchChangesClient struct {
+	grpc.ClientStream
+}
+
+func (x *metaStoreWatchChangesClient) Recv() (*FileChange, error) {
+	m := new(FileChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *metaStoreClient) GetChangesSince(ctx context.Context, in *ChangeCursor, opts ...grpc.CallOption) (*FileInfoChanges, error) {
+	out := new(FileInfoChanges)
+	err := c.cc.Invoke(ctx, "/surfstore.MetaStore/GetChangesSince", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) ListFileVersions(ctx context.Context, in *FileName, opts ...grpc.CallOption) (*FileVersions, error) {
+	out := new(FileVersions)
+	err := c.cc.Invoke(ctx, "/surfstore.MetaStore/ListFileVersions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) GetFileVersion(ctx context.Context, in *FileVersion, opts ...grpc.CallOption) (*FileMetaData, error) {
+	out := new(FileMetaData)
+	err := c.cc.Invoke(ctx, "/surfstore.MetaStore/GetFileVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) GetReferencedBlockHashes(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*BlockHashes, error) {
+	out := new(BlockHashes)
+	err := c.cc.Invoke(ctx, "/surfstore.MetaStore/GetReferencedBlockHashes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) AddBlockStore(ctx context.Context, in *BlockStoreMember, opts ...grpc.CallOption) (*BlockStoreAddrs, error) {
+	out := new(BlockStoreAddrs)
+	err := c.cc.Invoke(ctx, "/surfstore.MetaStore/AddBlockStore", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) RemoveBlockStore(ctx context.Context, in *BlockStoreMember, opts ...grpc.CallOption) (*BlockStoreAddrs, error) {
+	out := new(BlockStoreAddrs)
+	err := c.cc.Invoke(ctx, "/surfstore.MetaStore/RemoveBlockStore", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetaStoreServer is the server API for MetaStore service.
 // All implementations must embed UnimplementedMetaStoreServer
 // for forward compatibility
@@ -275,6 +538,13 @@ type MetaStoreServer interface {
 	UpdateFile(context.Context, *FileMetaData) (*Version, error)
 	GetBlockStoreMap(context.Context, *BlockHashes) (*BlockStoreMap, error)
 	GetBlockStoreAddrs(context.Context, *emptypb.Empty) (*BlockStoreAddrs, error)
+	WatchChanges(*WatchRequest, MetaStore_WatchChangesServer) error
+	GetChangesSince(context.Context, *ChangeCursor) (*FileInfoChanges, error)
+	ListFileVersions(context.Context, *FileName) (*FileVersions, error)
+	GetFileVersion(context.Context, *FileVersion) (*FileMetaData, error)
+	GetReferencedBlockHashes(context.Context, *emptypb.Empty) (*BlockHashes, error)
+	AddBlockStore(context.Context, *BlockStoreMember) (*BlockStoreAddrs, error)
+	RemoveBlockStore(context.Context, *BlockStoreMember) (*BlockStoreAddrs, error)
 	mustEmbedUnimplementedMetaStoreServer()
 }
 
@@ -294,6 +564,27 @@ func (UnimplementedMetaStoreServer) GetBlockStoreMap(context.Context, *BlockHash
 func (UnimplementedMetaStoreServer) GetBlockStoreAddrs(context.Context, *emptypb.Empty) (*BlockStoreAddrs, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetBlockStoreAddrs not implemented")
 }
+func (UnimplementedMetaStoreServer) WatchChanges(*WatchRequest, MetaStore_WatchChangesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchChanges not implemented")
+}
+func (UnimplementedMetaStoreServer) GetChangesSince(context.Context, *ChangeCursor) (*FileInfoChanges, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChangesSince not implemented")
+}
+func (UnimplementedMetaStoreServer) ListFileVersions(context.Context, *FileName) (*FileVersions, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFileVersions not implemented")
+}
+func (UnimplementedMetaStoreServer) GetFileVersion(context.Context, *FileVersion) (*FileMetaData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFileVersion not implemented")
+}
+func (UnimplementedMetaStoreServer) GetReferencedBlockHashes(context.Context, *emptypb.Empty) (*BlockHashes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReferencedBlockHashes not implemented")
+}
+func (UnimplementedMetaStoreServer) AddBlockStore(context.Context, *BlockStoreMember) (*BlockStoreAddrs, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddBlockStore not implemented")
+}
+func (UnimplementedMetaStoreServer) RemoveBlockStore(context.Context, *BlockStoreMember) (*BlockStoreAddrs, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveBlockStore not implemented")
+}
 func (UnimplementedMetaStoreServer) mustEmbedUnimplementedMetaStoreServer() {}
 
 // UnsafeMetaStoreServer may be embedded to opt out of forward compatibility for this service.
@@ -379,6 +670,135 @@ func _MetaStore_GetBlockStoreAddrs_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetaStore_WatchChanges_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MetaStoreServer).WatchChanges(m, &metaStoreWatchChangesServer{stream})
+}
+
+type MetaStore_WatchChangesServer interface {
+	Send(*FileChange) error
+	grpc.ServerStream
+}
+
+type metaStoreWatchChangesServer struct {
+	grpc.ServerStream
+}
+
+func (x *metaStoreWatchChangesServer) Send(m *FileChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _MetaStore_GetChangesSince_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeCursor)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).GetChangesSince(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/surfstore.MetaStore/GetChangesSince",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).GetChangesSince(ctx, req.(*ChangeCursor))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_ListFileVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).ListFileVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/surfstore.MetaStore/ListFileVersions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).ListFileVersions(ctx, req.(*FileName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_GetFileVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileVersion)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).GetFileVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/surfstore.MetaStore/GetFileVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).GetFileVersion(ctx, req.(*FileVersion))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_GetReferencedBlockHashes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).GetReferencedBlockHashes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/surfstore.MetaStore/GetReferencedBlockHashes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).GetReferencedBlockHashes(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_AddBlockStore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockStoreMember)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).AddBlockStore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/surfstore.MetaStore/AddBlockStore",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).AddBlockStore(ctx, req.(*BlockStoreMember))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_RemoveBlockStore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockStoreMember)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).RemoveBlockStore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/surfstore.MetaStore/RemoveBlockStore",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).RemoveBlockStore(ctx, req.(*BlockStoreMember))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MetaStore_ServiceDesc is the grpc.ServiceDesc for MetaStore service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -402,6 +822,194 @@ var MetaStore_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetBlockStoreAddrs",
 			Handler:    _MetaStore_GetBlockStoreAddrs_Handler,
 		},
+		{
+			MethodName: "GetChangesSince",
+			Handler:    _MetaStore_GetChangesSince_Handler,
+		},
+		{
+			MethodName: "ListFileVersions",
+			Handler:    _MetaStore_ListFileVersions_Handler,
+		},
+		{
+			MethodName: "GetFileVersion",
+			Handler:    _MetaStore_GetFileVersion_Handler,
+		},
+		{
+			MethodName: "GetReferencedBlockHashes",
+			Handler:    _MetaStore_GetReferencedBlockHashes_Handler,
+		},
+		{
+			MethodName: "AddBlockStore",
+			Handler:    _MetaStore_AddBlockStore_Handler,
+		},
+		{
+			MethodName: "RemoveBlockStore",
+			Handler:    _MetaStore_RemoveBlockStore_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchChanges",
+			Handler:       _MetaStore_WatchChanges_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pkg/surfstore/SurfStore.proto",
+}
+
+// RaftSurfstoreClient is the client API for RaftSurfstore service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RaftSurfstoreClient interface {
+	AppendEntries(ctx context.Context, in *AppendEntryInput, opts ...grpc.CallOption) (*AppendEntryOutput, error)
+	RequestVote(ctx context.Context, in *RequestVoteInput, opts ...grpc.CallOption) (*RequestVoteOutput, error)
+	InstallSnapshot(ctx context.Context, in *InstallSnapshotInput, opts ...grpc.CallOption) (*AppendEntryOutput, error)
+}
+
+type raftSurfstoreClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRaftSurfstoreClient(cc grpc.ClientConnInterface) RaftSurfstoreClient {
+	return &raftSurfstoreClient{cc}
+}
+
+func (c *raftSurfstoreClient) AppendEntries(ctx context.Context, in *AppendEntryInput, opts ...grpc.CallOption) (*AppendEntryOutput, error) {
+	out := new(AppendEntryOutput)
+	err := c.cc.Invoke(ctx, "/surfstore.RaftSurfstore/AppendEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftSurfstoreClient) RequestVote(ctx context.Context, in *RequestVoteInput, opts ...grpc.CallOption) (*RequestVoteOutput, error) {
+	out := new(RequestVoteOutput)
+	err := c.cc.Invoke(ctx, "/surfstore.RaftSurfstore/RequestVote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftSurfstoreClient) InstallSnapshot(ctx context.Context, in *InstallSnapshotInput, opts ...grpc.CallOption) (*AppendEntryOutput, error) {
+	out := new(AppendEntryOutput)
+	err := c.cc.Invoke(ctx, "/surfstore.RaftSurfstore/InstallSnapshot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RaftSurfstoreServer is the server API for RaftSurfstore service.
+// All implementations must embed UnimplementedRaftSurfstoreServer
+// for forward compatibility
+type RaftSurfstoreServer interface {
+	AppendEntries(context.Context, *AppendEntryInput) (*AppendEntryOutput, error)
+	RequestVote(context.Context, *RequestVoteInput) (*RequestVoteOutput, error)
+	InstallSnapshot(context.Context, *InstallSnapshotInput) (*AppendEntryOutput, error)
+	mustEmbedUnimplementedRaftSurfstoreServer()
+}
+
+// UnimplementedRaftSurfstoreServer must be embedded to have forward compatible implementations.
+type UnimplementedRaftSurfstoreServer struct {
+}
+
+func (UnimplementedRaftSurfstoreServer) AppendEntries(context.Context, *AppendEntryInput) (*AppendEntryOutput, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AppendEntries not implemented")
+}
+func (UnimplementedRaftSurfstoreServer) RequestVote(context.Context, *RequestVoteInput) (*RequestVoteOutput, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestVote not implemented")
+}
+func (UnimplementedRaftSurfstoreServer) InstallSnapshot(context.Context, *InstallSnapshotInput) (*AppendEntryOutput, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InstallSnapshot not implemented")
+}
+func (UnimplementedRaftSurfstoreServer) mustEmbedUnimplementedRaftSurfstoreServer() {}
+
+// UnsafeRaftSurfstoreServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RaftSurfstoreServer will
+// result in compilation errors.
+type UnsafeRaftSurfstoreServer interface {
+	mustEmbedUnimplementedRaftSurfstoreServer()
+}
+
+func RegisterRaftSurfstoreServer(s grpc.ServiceRegistrar, srv RaftSurfstoreServer) {
+	s.RegisterService(&RaftSurfstore_ServiceDesc, srv)
+}
+
+func _RaftSurfstore_AppendEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AppendEntryInput)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftSurfstoreServer).AppendEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/surfstore.RaftSurfstore/AppendEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftSurfstoreServer).AppendEntries(ctx, req.(*AppendEntryInput))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RaftSurfstore_RequestVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestVoteInput)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftSurfstoreServer).RequestVote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/surfstore.RaftSurfstore/RequestVote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftSurfstoreServer).RequestVote(ctx, req.(*RequestVoteInput))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RaftSurfstore_InstallSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstallSnapshotInput)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftSurfstoreServer).InstallSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/surfstore.RaftSurfstore/InstallSnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftSurfstoreServer).InstallSnapshot(ctx, req.(*InstallSnapshotInput))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RaftSurfstore_ServiceDesc is the grpc.ServiceDesc for RaftSurfstore service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RaftSurfstore_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "surfstore.RaftSurfstore",
+	HandlerType: (*RaftSurfstoreServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AppendEntries",
+			Handler:    _RaftSurfstore_AppendEntries_Handler,
+		},
+		{
+			MethodName: "RequestVote",
+			Handler:    _RaftSurfstore_RequestVote_Handler,
+		},
+		{
+			MethodName: "InstallSnapshot",
+			Handler:    _RaftSurfstore_InstallSnapshot_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/surfstore/SurfStore.proto",
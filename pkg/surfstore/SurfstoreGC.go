@@ -0,0 +1,54 @@
+package surfstore
+
+import (
+	context "context"
+	"fmt"
+	"time"
+)
+
+// CollectGarbage runs one mark-and-sweep pass over every BlockStore. It
+// marks the blocks used by any file version the MetaStore retains, then
+// asks each BlockStore to delete the blocks it holds that were not marked.
+// Blocks stored or confirmed by MissingBlocks within gracePeriod survive,
+// they may belong to a sync that has not committed its UpdateFile yet.
+// Returns the deleted hashes of each BlockStore.
+func CollectGarbage(ctx context.Context, client *RPCClient, gracePeriod time.Duration) (map[string][]string, error) {
+	// mark before listing, a block uploaded in between is within the
+	// grace period
+	var referencedHashes []string
+	if err := client.GetReferencedBlockHashes(ctx, &referencedHashes); err != nil {
+		return nil, fmt.Errorf("Error marking referenced blocks: %v", err)
+	}
+	referenced := make(map[string]bool, len(referencedHashes))
+	for _, hash := range referencedHashes {
+		referenced[hash] = true
+	}
+
+	var blockStoreAddrs []string
+	if err := client.GetBlockStoreAddrs(ctx, &blockStoreAddrs); err != nil {
+		return nil, fmt.Errorf("Error getting block store addresses: %v", err)
+	}
+	deleted := make(map[string][]string)
+	for _, blockStoreAddr := range blockStoreAddrs {
+		var storedHashes []string
+		if err := client.GetBlockHashes(ctx, blockStoreAddr, &storedHashes); err != nil {
+			return deleted, fmt.Errorf("Error listing blocks on %s: %v", blockStoreAddr, err)
+		}
+		unreferenced := []string{}
+		for _, hash := range storedHashes {
+			if !referenced[hash] {
+				unreferenced = append(unreferenced, hash)
+			}
+		}
+		if len(unreferenced) == 0 {
+			deleted[blockStoreAddr] = []string{}
+			continue
+		}
+		var deletedHashes []string
+		if err := client.DeleteBlocks(ctx, unreferenced, gracePeriod, blockStoreAddr, &deletedHashes); err != nil {
+			return deleted, fmt.Errorf("Error deleting blocks on %s: %v", blockStoreAddr, err)
+		}
+		deleted[blockStoreAddr] = deletedHashes
+	}
+	return deleted, nil
+}
@@ -0,0 +1,105 @@
+package surfstore
+
+import (
+	context "context"
+	"sort"
+	"testing"
+	"time"
+)
+
+func putTestBlocks(t *testing.T, client *RPCClient, addr string, data ...string) []string {
+	t.Helper()
+	blocks := []*Block{}
+	hashes := []string{}
+	for _, d := range data {
+		hash, block := testBlock(d)
+		blocks = append(blocks, block)
+		hashes = append(hashes, hash)
+	}
+	var succ bool
+	if err := client.PutBlocks(context.Background(), blocks, addr, &succ); err != nil {
+		t.Fatal(err)
+	}
+	return hashes
+}
+
+func storedHashes(t *testing.T, client *RPCClient, addr string) map[string]bool {
+	t.Helper()
+	var hashes []string
+	if err := client.GetBlockHashes(context.Background(), addr, &hashes); err != nil {
+		t.Fatal(err)
+	}
+	stored := map[string]bool{}
+	for _, hash := range hashes {
+		stored[hash] = true
+	}
+	return stored
+}
+
+// A sweep deletes the unreferenced blocks stored before the grace period,
+// and keeps the referenced ones and those stored or confirmed by
+// MissingBlocks within it
+func TestCollectGarbage(t *testing.T) {
+	const gracePeriod = 300 * time.Millisecond
+	_, addr := startSyncServer(t)
+	baseDir := t.TempDir()
+	writeTestFile(t, baseDir, "f.txt", "referenced blocks")
+	syncDir(t, addr, baseDir)
+	client := NewSurfstoreRPCClient(addr, baseDir, 4)
+	defer client.Close()
+	ctx := context.Background()
+
+	old := putTestBlocks(t, client, addr, "old orphan")
+	touched := putTestBlocks(t, client, addr, "touched orphan")
+	time.Sleep(gracePeriod)
+	// a sync that is about to reuse the block confirms it is still there
+	var missing []string
+	if err := client.MissingBlocks(ctx, touched, addr, &missing); err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Fatalf("%v reported missing", missing)
+	}
+	fresh := putTestBlocks(t, client, addr, "fresh orphan")
+
+	deleted, err := CollectGarbage(ctx, client, gracePeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted[addr]) != 1 || deleted[addr][0] != old[0] {
+		t.Fatalf("deleted %v, want only the old orphan %s", deleted[addr], old[0])
+	}
+	stored := storedHashes(t, client, addr)
+	for _, hash := range append(touched, fresh...) {
+		if !stored[hash] {
+			t.Fatalf("%s was deleted within the grace period", hash)
+		}
+	}
+
+	// once the grace period is over only the referenced blocks are left
+	time.Sleep(gracePeriod)
+	deleted, err = CollectGarbage(ctx, client, gracePeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := deleted[addr]
+	want := append(touched, fresh...)
+	sort.Strings(got)
+	sort.Strings(want)
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("deleted %v, want %v", got, want)
+	}
+	localIndex, err := LoadMetaFromMetaFile(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored = storedHashes(t, client, addr)
+	if len(stored) != len(localIndex["f.txt"].BlockHashList) {
+		t.Fatalf("%d blocks left, want the %d blocks of f.txt", len(stored), len(localIndex["f.txt"].BlockHashList))
+	}
+	for _, hash := range localIndex["f.txt"].BlockHashList {
+		if !stored[hash] {
+			t.Fatalf("referenced block %s was deleted", hash)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 
@@ -29,6 +28,35 @@ func ConcatPath(baseDir, fileDir string) string {
 	return baseDir + "/" + fileDir
 }
 
+// writeFileAtomic writes data to a temp file next to path, fsyncs it and
+// renames it over path.
+func writeFileAtomic(path string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if err := tmpFile.Chmod(0644); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return err
+	}
+	return syncFile(filepath.Dir(path))
+}
+
 /*
 	Writing Local Metadata File Related
 */
@@ -47,38 +75,72 @@ const getFileMetaDataTable string = `select fileName, version, hashIndex, hashVa
 
 const updateTuple string = `update indexes set hashValue = ?, version = ? where fileName = ? and version = ? and hashIndex = ?;`
 
-// WriteMetaFile writes the file meta map back to local metadata file index.db
+// WriteMetaFile writes the file meta map back to local metadata file index.db.
+// The new index is built in a temp file and renamed over index.db, so an
+// interrupted write leaves the previous index in place.
 func WriteMetaFile(fileMetas map[string]*FileMetaData, baseDir string) error {
-	// remove index.db file if it exists
 	outputMetaPath := ConcatPath(baseDir, DEFAULT_META_FILENAME)
-	if _, err := os.Stat(outputMetaPath); err == nil {
-		e := os.Remove(outputMetaPath)
-		if e != nil {
-			log.Fatal("Error During Meta Write Back")
-		}
+	tmpFile, err := os.CreateTemp(baseDir, CLIENT_FILE_PREFIX+DEFAULT_META_FILENAME+"*")
+	if err != nil {
+		return err
 	}
-	db, err := sql.Open("sqlite3", outputMetaPath)
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath)
+
+	db, err := sql.Open("sqlite3", tmpPath)
 	if err != nil {
-		log.Fatal("Error During Meta Write Back")
+		return err
+	}
+	if err := writeMetaTable(db, fileMetas); err != nil {
+		db.Close()
+		return err
 	}
-	statement, err := db.Prepare(createTable)
+	if err := db.Close(); err != nil {
+		return err
+	}
+	if err := syncFile(tmpPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, outputMetaPath); err != nil {
+		return err
+	}
+	return syncFile(baseDir)
+}
+
+// writeMetaTable fills an empty index database in a single transaction
+func writeMetaTable(db *sql.DB, fileMetas map[string]*FileMetaData) error {
+	tx, err := db.Begin()
 	if err != nil {
-		log.Fatal("Error During Meta Write Back")
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(createTable); err != nil {
+		return err
 	}
-	statement.Exec()
-	statement, err = db.Prepare(insertTuple)
+	statement, err := tx.Prepare(insertTuple)
 	if err != nil {
-		log.Fatal("Error During Meta Write Back")
+		return err
 	}
+	defer statement.Close()
 	for _, fileMeta := range fileMetas {
 		for i, hash := range fileMeta.BlockHashList {
-			_, err = statement.Exec(fileMeta.Filename, fileMeta.Version, i, hash)
-			if err != nil {
-				log.Fatal("Error During Meta Write Back")
+			if _, err := statement.Exec(fileMeta.Filename, fileMeta.Version, i, hash); err != nil {
+				return err
 			}
 		}
 	}
-	return nil
+	return tx.Commit()
+}
+
+// syncFile fsyncs the file or directory at path
+func syncFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
 }
 
 /*
@@ -93,40 +155,43 @@ const getTuplesByFileName string = `select version, hashIndex, hashValue from in
 // You can use this function to load the index.db file in this project.
 func LoadMetaFromMetaFile(baseDir string) (fileMetaMap map[string]*FileMetaData, e error) {
 	metaFilePath, _ := filepath.Abs(ConcatPath(baseDir, DEFAULT_META_FILENAME))
-	fmt.Println(metaFilePath)
 
 	fileMetaMap = make(map[string]*FileMetaData)
 	metaFileStats, e := os.Stat(metaFilePath)
 	if e != nil || metaFileStats.IsDir() {
-		fmt.Println("META FILE DOES NOT EXIST")
+		// first sync, nothing synced yet
 		return fileMetaMap, nil
 	}
-	fmt.Println("META FILE EXISTS")
 
 	db, err := sql.Open("sqlite3", metaFilePath)
 	if err != nil {
-		fmt.Println(err.Error())
+		return nil, err
 	}
+	defer db.Close()
 
 	// create table in .db file
 	rows, err := db.Query(getFileMetaDataTable)
 	if err != nil {
-		log.Fatal("Error When Querying Meta")
+		return nil, fmt.Errorf("Error querying %s: %v", DEFAULT_META_FILENAME, err)
 	}
+	defer rows.Close()
 	var fileName string
 	var version int
 	var hashIndex int
 	var hashValue string
 
 	for rows.Next() {
-		fmt.Println("READING ROW")
-		rows.Scan(&fileName, &version, &hashIndex, &hashValue)
-		fmt.Println(fileName, version, hashIndex, hashValue)
+		if err := rows.Scan(&fileName, &version, &hashIndex, &hashValue); err != nil {
+			return nil, err
+		}
 		if _, ok := fileMetaMap[fileName]; !ok {
 			fileMetaMap[fileName] = &FileMetaData{Filename: fileName, Version: int32(version), BlockHashList: []string{}}
 		}
 		fileMetaMap[fileName].BlockHashList = append(fileMetaMap[fileName].BlockHashList, hashValue)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
 	return fileMetaMap, nil
 
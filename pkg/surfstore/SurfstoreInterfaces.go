@@ -2,10 +2,24 @@ package surfstore
 
 import (
 	context "context"
+	"errors"
+	"time"
 
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
+var ERR_BLOCK_NOT_FOUND = errors.New("Block not found")
+
+// Returned by UpdateFile when the file is no longer at the version before
+// the one being committed, another client updated it first
+var ERR_VERSION_MISMATCH = errors.New("Version mismatch")
+
+type BlockInfo struct {
+	Hash     string
+	Size     int64
+	StoredAt time.Time
+}
+
 type MetaStoreInterface interface {
 	// Retrieves the server's FileInfoMap
 	GetFileInfoMap(ctx context.Context, _ *emptypb.Empty) (*FileInfoMap, error)
@@ -18,6 +32,37 @@ type MetaStoreInterface interface {
 
 	// Retrieve all BlockStore Addresses
 	GetBlockStoreAddrs(ctx context.Context, _ *emptypb.Empty) (*BlockStoreAddrs, error)
+
+	// Stream the changes committed after a sequence number, and every
+	// change committed while the stream is open
+	WatchChanges(request *WatchRequest, stream MetaStore_WatchChangesServer) error
+
+	// Retrieve only the FileInfoMap entries changed after a cursor
+	GetChangesSince(ctx context.Context, cursor *ChangeCursor) (*FileInfoChanges, error)
+
+	// Retrieve every retained version of a file, oldest first
+	ListFileVersions(ctx context.Context, fileName *FileName) (*FileVersions, error)
+
+	// Retrieve one retained version of a file
+	GetFileVersion(ctx context.Context, fileVersion *FileVersion) (*FileMetaData, error)
+
+	// Retrieve the hashes of every block a retained file version uses
+	GetReferencedBlockHashes(ctx context.Context, _ *emptypb.Empty) (*BlockHashes, error)
+	AddBlockStore(ctx context.Context, member *BlockStoreMember) (*BlockStoreAddrs, error)
+	RemoveBlockStore(ctx context.Context, member *BlockStoreMember) (*BlockStoreAddrs, error)
+}
+
+type RaftInterface interface {
+	MetaStoreInterface
+
+	// Replicate log entries to a follower, also used as heartbeat
+	AppendEntries(ctx context.Context, input *AppendEntryInput) (*AppendEntryOutput, error)
+
+	// Ask for this server's vote in an election
+	RequestVote(ctx context.Context, input *RequestVoteInput) (*RequestVoteOutput, error)
+
+	// Replace a lagging follower's state with the leader's snapshot
+	InstallSnapshot(ctx context.Context, input *InstallSnapshotInput) (*AppendEntryOutput, error)
 }
 
 type BlockStoreInterface interface {
@@ -34,18 +79,65 @@ type BlockStoreInterface interface {
 
 	// Get which blocks are on this BlockStore server
 	GetBlockHashes(ctx context.Context, _ *emptypb.Empty) (*BlockHashes, error)
+
+	// Put every block sent over a single stream
+	PutBlocks(stream BlockStore_PutBlocksServer) error
+
+	// Stream back the blocks for a list of hashes
+	GetBlocks(blockHashesIn *BlockHashes, stream BlockStore_GetBlocksServer) error
+
+	// Delete the given blocks unless they were stored within the grace
+	// period, returns the hashes that were deleted
+	DeleteBlocks(ctx context.Context, request *DeleteBlocksRequest) (*BlockHashes, error)
+}
+
+// BlockStorage is where a BlockStore keeps its blocks, keyed by hash
+type BlockStorage interface {
+	// Get the block stored under hash, ERR_BLOCK_NOT_FOUND if there is none
+	Get(hash string) (*Block, error)
+
+	// Store a block under hash, storing an existing hash again is a no-op
+	Put(hash string, block *Block) error
+
+	// Whether a block is stored under hash
+	Has(hash string) (bool, error)
+
+	// Hashes of every stored block
+	List() ([]string, error)
+
+	// Remove the block stored under hash, removing a missing block is a no-op
+	Delete(hash string) error
+
+	// Size and storage time of the block under hash, ERR_BLOCK_NOT_FOUND if
+	// there is none
+	Stat(hash string) (*BlockInfo, error)
+
+	// Reset the storage time of the block under hash to now, touching a
+	// missing block is a no-op
+	Touch(hash string) error
 }
 
 type ClientInterface interface {
 	// MetaStore
-	GetFileInfoMap(serverFileInfoMap *map[string]*FileMetaData) error
-	UpdateFile(fileMetaData *FileMetaData, latestVersion *int32) error
-	GetBlockStoreMap(blockHashesIn []string, blockStoreMap *map[string][]string) error
-	GetBlockStoreAddrs(blockStoreAddrs *[]string) error
+	GetFileInfoMap(ctx context.Context, serverFileInfoMap *map[string]*FileMetaData) error
+	UpdateFile(ctx context.Context, fileMetaData *FileMetaData, latestVersion *int32) error
+	GetBlockStoreMap(ctx context.Context, blockHashesIn []string, blockStoreMap *map[string][]string) error
+	GetBlockReplicas(ctx context.Context, blockHashesIn []string, blockReplicas *map[string][]string) error
+	GetBlockStoreAddrs(ctx context.Context, blockStoreAddrs *[]string) error
+	WatchChanges(ctx context.Context, afterSequence int64, onChange func(change *FileChange) error) error
+	GetChangesSince(ctx context.Context, cursor int64, changes *FileInfoChanges) error
+	ListFileVersions(ctx context.Context, fileName string, versions *[]*FileMetaData) error
+	GetFileVersion(ctx context.Context, fileName string, version int32, fileMetaData *FileMetaData) error
+	GetReferencedBlockHashes(ctx context.Context, blockHashes *[]string) error
+	AddBlockStore(ctx context.Context, blockStoreAddr string, weight int, blockStoreAddrs *[]string) error
+	RemoveBlockStore(ctx context.Context, blockStoreAddr string, blockStoreAddrs *[]string) error
 
 	// BlockStore
-	GetBlock(blockHash string, blockStoreAddr string, block *Block) error
-	PutBlock(block *Block, blockStoreAddr string, succ *bool) error
-	MissingBlocks(blockHashesIn []string, blockStoreAddr string, blockHashesOut *[]string) error
-	GetBlockHashes(blockStoreAddr string, blockHashes *[]string) error
+	GetBlock(ctx context.Context, blockHash string, blockStoreAddr string, block *Block) error
+	PutBlock(ctx context.Context, block *Block, blockStoreAddr string, succ *bool) error
+	MissingBlocks(ctx context.Context, blockHashesIn []string, blockStoreAddr string, blockHashesOut *[]string) error
+	GetBlockHashes(ctx context.Context, blockStoreAddr string, blockHashes *[]string) error
+	PutBlocks(ctx context.Context, blocks []*Block, blockStoreAddr string, succ *bool) error
+	GetBlocks(ctx context.Context, blockHashesIn []string, blockStoreAddr string, blocks *[]*Block) error
+	DeleteBlocks(ctx context.Context, blockHashesIn []string, gracePeriod time.Duration, blockStoreAddr string, blockHashesOut *[]string) error
 }
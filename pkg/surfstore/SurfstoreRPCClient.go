@@ -2,163 +2,552 @@ package surfstore
 
 import (
 	context "context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 type RPCClient struct {
-	MetaStoreAddr string
-	BaseDir       string
-	BlockSize     int
+	// Addresses of every MetaStore replica, calls go to whichever of them
+	// is currently the leader
+	MetaStoreAddrs []string
+	BaseDir        string
+	BlockSize      int
+	// Decides where files are cut into blocks, nil cuts fixed size blocks
+	// of BlockSize bytes
+	Chunker Chunker
+	// How long a BlockStore may take to answer a GetBlock call, or to
+	// stream the next block of a GetBlocks call
+	GetBlockTimeout time.Duration
+
+	// Index into MetaStoreAddrs of the last known leader, calls made
+	// concurrently during a sync all read and update it
+	leaderIndex atomic.Int32
+	connsOnce   sync.Once
+	conns       *connPool
+}
+
+// connPool holds one long lived connection per server address.
+type connPool struct {
+	mtx   sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// connPool returns the client's pool, creating it for a client that was not
+// made by NewSurfstoreRPCClient
+func (surfClient *RPCClient) connPool() *connPool {
+	surfClient.connsOnce.Do(func() {
+		if surfClient.conns == nil {
+			surfClient.conns = &connPool{conns: map[string]*grpc.ClientConn{}}
+		}
+	})
+	return surfClient.conns
 }
 
-func (surfClient *RPCClient) GetBlock(blockHash string, blockStoreAddr string, block *Block) error {
+// getConn returns the pooled connection to addr, dialing it on first use.
+// A connection that has failed or been shut down is replaced by a fresh
+// one rather than waiting out gRPC's reconnect backoff.
+func (surfClient *RPCClient) getConn(addr string) (*grpc.ClientConn, error) {
+	pool := surfClient.connPool()
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	if conn, ok := pool.conns[addr]; ok {
+		state := conn.GetState()
+		if state != connectivity.TransientFailure && state != connectivity.Shutdown {
+			return conn, nil
+		}
+		conn.Close()
+		delete(pool.conns, addr)
+	}
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	pool.conns[addr] = conn
+	return conn, nil
+}
+
+// Close closes every pooled connection. The client can still be used
+// afterwards, connections are dialed again as needed.
+func (surfClient *RPCClient) Close() error {
+	pool := surfClient.connPool()
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	var firstErr error
+	for addr, conn := range pool.conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(pool.conns, addr)
+	}
+	return firstErr
+}
+
+func (surfClient *RPCClient) GetBlock(ctx context.Context, blockHash string, blockStoreAddr string, block *Block) error {
 	// connect to the server
-	conn, err := grpc.Dial(blockStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := surfClient.getConn(blockStoreAddr)
 	if err != nil {
 		return err
 	}
 	c := NewBlockStoreClient(conn)
 
 	// perform the call
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(ctx, surfClient.blockTimeout())
 	defer cancel()
 	b, err := c.GetBlock(ctx, &BlockHash{Hash: blockHash})
 	if err != nil {
-		conn.Close()
 		return err
 	}
 	block.BlockData = b.BlockData
 	block.BlockSize = b.BlockSize
 
-	// close the connection
-	return conn.Close()
+	return nil
 }
 
-func (surfClient *RPCClient) PutBlock(block *Block, blockStoreAddr string, succ *bool) error {
-	conn, err := grpc.Dial(blockStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func (surfClient *RPCClient) PutBlock(ctx context.Context, block *Block, blockStoreAddr string, succ *bool) error {
+	conn, err := surfClient.getConn(blockStoreAddr)
 	// fmt.Println("PUTBLOCK: Connecting to block store at ", blockStoreAddr)
 	if err != nil {
 		return err
 	}
 	c := NewBlockStoreClient(conn)
-	_, err = c.PutBlock(context.Background(), block)
+	_, err = c.PutBlock(ctx, block)
 	if err != nil {
 		// fmt.Println("PUTBLOCK: Error connecting to block store") //ERROR OCCURING HERE
-		conn.Close()
 		return err
 	}
 	*succ = true
-	return conn.Close()
+	return nil
 }
 
-func (surfClient *RPCClient) MissingBlocks(blockHashesIn []string, blockStoreAddr string, blockHashesOut *[]string) error {
-	conn, err := grpc.Dial(blockStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// PutBlocks uploads blocks to blockStoreAddr over a single stream
+func (surfClient *RPCClient) PutBlocks(ctx context.Context, blocks []*Block, blockStoreAddr string, succ *bool) error {
+	conn, err := surfClient.getConn(blockStoreAddr)
 	if err != nil {
 		return err
 	}
 	c := NewBlockStoreClient(conn)
-	missingHashes, err := c.MissingBlocks(context.Background(), &BlockHashes{Hashes: blockHashesIn})
+	stream, err := c.PutBlocks(ctx)
 	if err != nil {
-		conn.Close()
 		return err
 	}
-	*blockHashesOut = missingHashes.Hashes
-	return conn.Close()
+	for _, block := range blocks {
+		if err := stream.Send(block); err != nil {
+			// the server's error is reported by CloseAndRecv
+			break
+		}
+	}
+	success, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	*succ = success.Flag
+	return nil
 }
 
-func (surfClient *RPCClient) GetFileInfoMap(serverFileInfoMap *map[string]*FileMetaData) error {
-	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// GetBlocks downloads the blocks for blockHashesIn from blockStoreAddr over
+// a single stream, in the same order as blockHashesIn. It fails with
+// DeadlineExceeded when the BlockStore sends no block for GetBlockTimeout.
+func (surfClient *RPCClient) GetBlocks(ctx context.Context, blockHashesIn []string, blockStoreAddr string, blocks *[]*Block) error {
+	conn, err := surfClient.getConn(blockStoreAddr)
 	if err != nil {
 		return err
 	}
-	c := NewMetaStoreClient(conn)
-	infoMap, err := c.GetFileInfoMap(context.Background(), &emptypb.Empty{})
+	c := NewBlockStoreClient(conn)
+	// the timeout restarts with every block, a large batch may take
+	// longer as a whole
+	timeout := surfClient.blockTimeout()
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	timer := time.AfterFunc(timeout, cancel)
+	defer timer.Stop()
+	received := make([]*Block, 0, len(blockHashesIn))
+	err = func() error {
+		stream, err := c.GetBlocks(streamCtx, &BlockHashes{Hashes: blockHashesIn})
+		if err != nil {
+			return err
+		}
+		for {
+			block, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			timer.Reset(timeout)
+			received = append(received, block)
+		}
+	}()
 	if err != nil {
-		conn.Close()
+		if ctx.Err() == nil && streamCtx.Err() != nil {
+			return status.Errorf(codes.DeadlineExceeded, "BlockStore %s sent no block for %v", blockStoreAddr, timeout)
+		}
 		return err
 	}
-	*serverFileInfoMap = infoMap.FileInfoMap
-	return conn.Close()
+	if len(received) != len(blockHashesIn) {
+		return fmt.Errorf("Expected %d blocks from %s, got %d", len(blockHashesIn), blockStoreAddr, len(received))
+	}
+	*blocks = received
+	return nil
 }
 
-func (surfClient *RPCClient) UpdateFile(fileMetaData *FileMetaData, latestVersion *int32) error {
-	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// blockTimeout is GetBlockTimeout, DEFAULT_GET_BLOCK_TIMEOUT if unset
+func (surfClient *RPCClient) blockTimeout() time.Duration {
+	if surfClient.GetBlockTimeout == 0 {
+		return DEFAULT_GET_BLOCK_TIMEOUT
+	}
+	return surfClient.GetBlockTimeout
+}
+
+// DeleteBlocks asks blockStoreAddr to delete blockHashesIn, except for
+// blocks stored within gracePeriod, and returns the hashes it deleted
+func (surfClient *RPCClient) DeleteBlocks(ctx context.Context, blockHashesIn []string, gracePeriod time.Duration, blockStoreAddr string, blockHashesOut *[]string) error {
+	conn, err := surfClient.getConn(blockStoreAddr)
 	if err != nil {
 		return err
 	}
-	c := NewMetaStoreClient(conn)
-	newVersion, err := c.UpdateFile(context.Background(), fileMetaData)
+	c := NewBlockStoreClient(conn)
+	deleted, err := c.DeleteBlocks(ctx, &DeleteBlocksRequest{Hashes: blockHashesIn, GracePeriodMillis: gracePeriod.Milliseconds()})
 	if err != nil {
-		conn.Close()
 		return err
 	}
-	*latestVersion = newVersion.Version
-	return conn.Close()
+	*blockHashesOut = deleted.Hashes
+	return nil
 }
 
-func (surfClient *RPCClient) GetBlockStoreMap(blockHashesIn []string, blockStoreMap *map[string][]string) error {
-	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func (surfClient *RPCClient) MissingBlocks(ctx context.Context, blockHashesIn []string, blockStoreAddr string, blockHashesOut *[]string) error {
+	conn, err := surfClient.getConn(blockStoreAddr)
 	if err != nil {
 		return err
 	}
-	c := NewMetaStoreClient(conn)
-	blockStoreMapProto, err := c.GetBlockStoreMap(context.Background(), &BlockHashes{Hashes: blockHashesIn})
+	c := NewBlockStoreClient(conn)
+	missingHashes, err := c.MissingBlocks(ctx, &BlockHashes{Hashes: blockHashesIn})
 	if err != nil {
-		conn.Close()
 		return err
 	}
-	stringBlockStoreMap := make(map[string][]string)
-	for k, v := range blockStoreMapProto.BlockStoreMap {
-		stringBlockStoreMap[k] = v.Hashes
-	}
-	*blockStoreMap = stringBlockStoreMap
-	return conn.Close()
+	*blockHashesOut = missingHashes.Hashes
+	return nil
+}
 
+func (surfClient *RPCClient) GetFileInfoMap(ctx context.Context, serverFileInfoMap *map[string]*FileMetaData) error {
+	return surfClient.callMetaStore(ctx, func(c MetaStoreClient, opts ...grpc.CallOption) error {
+		infoMap, err := c.GetFileInfoMap(ctx, &emptypb.Empty{}, opts...)
+		if err != nil {
+			return err
+		}
+		*serverFileInfoMap = infoMap.FileInfoMap
+		return nil
+	})
 }
 
-func (surfClient *RPCClient) GetBlockStoreAddrs(blockStoreAddrs *[]string) error {
-	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return err
+// UpdateFile commits fileMetaData. An attempt that failed as Unavailable
+// may still have been committed by a leader whose answer was lost, so when
+// the retry then fails with a version mismatch the committed version is
+// checked: if it is the update that was sent, the call succeeded.
+func (surfClient *RPCClient) UpdateFile(ctx context.Context, fileMetaData *FileMetaData, latestVersion *int32) error {
+	mayHaveLanded := false
+	return surfClient.callMetaStore(ctx, func(c MetaStoreClient, opts ...grpc.CallOption) error {
+		newVersion, err := c.UpdateFile(ctx, fileMetaData, opts...)
+		if status.Code(err) == codes.Unavailable {
+			mayHaveLanded = true
+		}
+		if status.Code(err) == codes.Aborted {
+			if mayHaveLanded && isCommitted(ctx, c, fileMetaData) {
+				*latestVersion = fileMetaData.Version
+				return nil
+			}
+			return ERR_VERSION_MISMATCH
+		}
+		if err != nil {
+			return err
+		}
+		*latestVersion = newVersion.Version
+		return nil
+	})
+}
+
+// isCommitted reports whether fileMetaData is the committed version of its
+// file, or one the MetaStore still retains
+func isCommitted(ctx context.Context, c MetaStoreClient, fileMetaData *FileMetaData) bool {
+	committed, err := c.GetFileVersion(ctx, &FileVersion{Filename: fileMetaData.Filename, Version: fileMetaData.Version})
+	if err != nil || len(committed.BlockHashList) != len(fileMetaData.BlockHashList) {
+		return false
 	}
-	c := NewMetaStoreClient(conn)
-	blockStoreAddrsProto, err := c.GetBlockStoreAddrs(context.Background(), &emptypb.Empty{})
-	if err != nil {
-		conn.Close()
-		return err
+	for i, hash := range committed.BlockHashList {
+		if hash != fileMetaData.BlockHashList[i] {
+			return false
+		}
 	}
-	*blockStoreAddrs = blockStoreAddrsProto.BlockStoreAddrs
-	return conn.Close()
+	return true
+}
+
+// GetChangesSince fetches the files changed after cursor, see
+// MetaStore.GetChangesSince
+func (surfClient *RPCClient) GetChangesSince(ctx context.Context, cursor int64, changes *FileInfoChanges) error {
+	return surfClient.callMetaStore(ctx, func(c MetaStoreClient, opts ...grpc.CallOption) error {
+		fileInfoChanges, err := c.GetChangesSince(ctx, &ChangeCursor{Cursor: cursor}, opts...)
+		if err != nil {
+			return err
+		}
+		changes.FileInfoMap = fileInfoChanges.FileInfoMap
+		changes.Cursor = fileInfoChanges.Cursor
+		changes.Full = fileInfoChanges.Full
+		return nil
+	})
+}
+
+func (surfClient *RPCClient) ListFileVersions(ctx context.Context, fileName string, versions *[]*FileMetaData) error {
+	return surfClient.callMetaStore(ctx, func(c MetaStoreClient, opts ...grpc.CallOption) error {
+		fileVersions, err := c.ListFileVersions(ctx, &FileName{Filename: fileName}, opts...)
+		if err != nil {
+			return err
+		}
+		*versions = fileVersions.Versions
+		return nil
+	})
+}
+
+func (surfClient *RPCClient) GetFileVersion(ctx context.Context, fileName string, version int32, fileMetaData *FileMetaData) error {
+	return surfClient.callMetaStore(ctx, func(c MetaStoreClient, opts ...grpc.CallOption) error {
+		oldFileMetaData, err := c.GetFileVersion(ctx, &FileVersion{Filename: fileName, Version: version}, opts...)
+		if err != nil {
+			return err
+		}
+		fileMetaData.Filename = oldFileMetaData.Filename
+		fileMetaData.Version = oldFileMetaData.Version
+		fileMetaData.BlockHashList = oldFileMetaData.BlockHashList
+		return nil
+	})
+}
+
+func (surfClient *RPCClient) GetReferencedBlockHashes(ctx context.Context, blockHashes *[]string) error {
+	return surfClient.callMetaStore(ctx, func(c MetaStoreClient, opts ...grpc.CallOption) error {
+		referenced, err := c.GetReferencedBlockHashes(ctx, &emptypb.Empty{}, opts...)
+		if err != nil {
+			return err
+		}
+		*blockHashes = referenced.Hashes
+		return nil
+	})
 }
 
-func (surfClient *RPCClient) GetBlockHashes(blockStoreAddr string, blockHashes *[]string) error {
-	conn, err := grpc.Dial(blockStoreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// AddBlockStore puts blockStoreAddr on the hash ring with weight and
+// returns the BlockStores on the ring afterwards
+func (surfClient *RPCClient) AddBlockStore(ctx context.Context, blockStoreAddr string, weight int, blockStoreAddrs *[]string) error {
+	mayHaveLanded := false
+	return surfClient.callMetaStore(ctx, func(c MetaStoreClient, opts ...grpc.CallOption) error {
+		addrs, err := c.AddBlockStore(ctx, &BlockStoreMember{Addr: blockStoreAddr, Weight: int32(weight)}, opts...)
+		if status.Code(err) == codes.Unavailable {
+			mayHaveLanded = true
+		}
+		if status.Code(err) == codes.AlreadyExists && mayHaveLanded {
+			// the attempt whose answer was lost added it
+			addrs, err = c.GetBlockStoreAddrs(ctx, &emptypb.Empty{})
+		}
+		if err != nil {
+			return err
+		}
+		*blockStoreAddrs = addrs.BlockStoreAddrs
+		return nil
+	})
+}
+
+// RemoveBlockStore takes blockStoreAddr off the hash ring and returns the
+// BlockStores on the ring afterwards
+func (surfClient *RPCClient) RemoveBlockStore(ctx context.Context, blockStoreAddr string, blockStoreAddrs *[]string) error {
+	mayHaveLanded := false
+	return surfClient.callMetaStore(ctx, func(c MetaStoreClient, opts ...grpc.CallOption) error {
+		addrs, err := c.RemoveBlockStore(ctx, &BlockStoreMember{Addr: blockStoreAddr}, opts...)
+		if status.Code(err) == codes.Unavailable {
+			mayHaveLanded = true
+		}
+		if status.Code(err) == codes.NotFound && mayHaveLanded {
+			// the attempt whose answer was lost removed it
+			addrs, err = c.GetBlockStoreAddrs(ctx, &emptypb.Empty{})
+		}
+		if err != nil {
+			return err
+		}
+		*blockStoreAddrs = addrs.BlockStoreAddrs
+		return nil
+	})
+}
+
+func (surfClient *RPCClient) GetBlockStoreMap(ctx context.Context, blockHashesIn []string, blockStoreMap *map[string][]string) error {
+	return surfClient.callMetaStore(ctx, func(c MetaStoreClient, opts ...grpc.CallOption) error {
+		blockStoreMapProto, err := c.GetBlockStoreMap(ctx, &BlockHashes{Hashes: blockHashesIn}, opts...)
+		if err != nil {
+			return err
+		}
+		stringBlockStoreMap := make(map[string][]string)
+		for k, v := range blockStoreMapProto.BlockStoreMap {
+			stringBlockStoreMap[k] = v.Hashes
+		}
+		*blockStoreMap = stringBlockStoreMap
+		return nil
+	})
+}
+
+// GetBlockReplicas returns the block servers each of blockHashesIn is
+// stored on, in the order they should be read from
+func (surfClient *RPCClient) GetBlockReplicas(ctx context.Context, blockHashesIn []string, blockReplicas *map[string][]string) error {
+	return surfClient.callMetaStore(ctx, func(c MetaStoreClient, opts ...grpc.CallOption) error {
+		blockStoreMapProto, err := c.GetBlockStoreMap(ctx, &BlockHashes{Hashes: blockHashesIn}, opts...)
+		if err != nil {
+			return err
+		}
+		replicas := make(map[string][]string)
+		for hash, addrs := range blockStoreMapProto.BlockReplicas {
+			replicas[hash] = addrs.BlockStoreAddrs
+		}
+		if len(replicas) == 0 {
+			// a MetaStore without replication
+			for blockStoreAddr, hashes := range blockStoreMapProto.BlockStoreMap {
+				for _, hash := range hashes.Hashes {
+					replicas[hash] = append(replicas[hash], blockStoreAddr)
+				}
+			}
+		}
+		*blockReplicas = replicas
+		return nil
+	})
+}
+
+func (surfClient *RPCClient) GetBlockStoreAddrs(ctx context.Context, blockStoreAddrs *[]string) error {
+	return surfClient.callMetaStore(ctx, func(c MetaStoreClient, opts ...grpc.CallOption) error {
+		blockStoreAddrsProto, err := c.GetBlockStoreAddrs(ctx, &emptypb.Empty{}, opts...)
+		if err != nil {
+			return err
+		}
+		*blockStoreAddrs = blockStoreAddrsProto.BlockStoreAddrs
+		return nil
+	})
+}
+
+func (surfClient *RPCClient) GetBlockHashes(ctx context.Context, blockStoreAddr string, blockHashes *[]string) error {
+	conn, err := surfClient.getConn(blockStoreAddr)
 	if err != nil {
 		return err
 	}
 	c := NewBlockStoreClient(conn)
-	hashes, err := c.GetBlockHashes(context.Background(), &emptypb.Empty{})
+	hashes, err := c.GetBlockHashes(ctx, &emptypb.Empty{})
 	if err != nil {
-		conn.Close()
 		return err
 	}
 	*blockHashes = hashes.Hashes
-	return conn.Close()
+	return nil
+}
+
+// WatchChanges calls onChange for every change the MetaStore commits after
+// afterSequence (-1 for changes from now on). It runs until ctx is
+// cancelled, onChange returns an error or the stream fails for good. A
+// stream that breaks is resumed after the last change seen, on whichever
+// replica is the leader. A stream that delivered changes before it broke
+// gets a fresh set of retries, so a long running watch survives any number
+// of leader changes.
+func (surfClient *RPCClient) WatchChanges(ctx context.Context, afterSequence int64, onChange func(change *FileChange) error) error {
+	for {
+		// the error a stream broke with after delivering changes
+		var broken error
+		err := surfClient.callMetaStore(ctx, func(c MetaStoreClient, opts ...grpc.CallOption) error {
+			broken = nil
+			stream, err := c.WatchChanges(ctx, &WatchRequest{AfterSequence: afterSequence}, opts...)
+			if err != nil {
+				return err
+			}
+			delivered := false
+			for {
+				change, err := stream.Recv()
+				if err != nil {
+					code := status.Code(err)
+					if delivered && (code == codes.FailedPrecondition || code == codes.Unavailable) {
+						broken = err
+						return nil
+					}
+					return err
+				}
+				if err := onChange(change); err != nil {
+					return err
+				}
+				afterSequence = change.Sequence
+				delivered = true
+			}
+		})
+		if err != nil || broken == nil {
+			return err
+		}
+	}
+}
+
+// callMetaStore runs call against the MetaStore leader. When the MetaStore
+// is replicated it starts with the last known leader and moves on to the
+// leader named by a follower, or simply the next replica, until one of them
+// accepts the call.
+func (surfClient *RPCClient) callMetaStore(ctx context.Context, call func(c MetaStoreClient, opts ...grpc.CallOption) error) error {
+	var err error
+	for attempt := 0; attempt < METASTORE_RETRIES*len(surfClient.MetaStoreAddrs); attempt++ {
+		if attempt > 0 && attempt%len(surfClient.MetaStoreAddrs) == 0 {
+			// every replica refused, give an election time to finish
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(METASTORE_RETRY_BACKOFF):
+			}
+		}
+		leaderIndex := int(surfClient.leaderIndex.Load())
+		addr := surfClient.MetaStoreAddrs[leaderIndex]
+		var conn *grpc.ClientConn
+		conn, err = surfClient.getConn(addr)
+		if err != nil {
+			return err
+		}
+		var trailer metadata.MD
+		err = call(NewMetaStoreClient(conn), grpc.Trailer(&trailer))
+		if err == nil {
+			return nil
+		}
+		code := status.Code(err)
+		if code != codes.FailedPrecondition && code != codes.Unavailable {
+			return err
+		}
+		next := (leaderIndex + 1) % len(surfClient.MetaStoreAddrs)
+		if leaderAddrs := trailer.Get(LEADER_ADDR_KEY); len(leaderAddrs) > 0 {
+			for i, metaStoreAddr := range surfClient.MetaStoreAddrs {
+				if metaStoreAddr == leaderAddrs[0] {
+					next = i
+				}
+			}
+		}
+		// another call may already have found the leader
+		surfClient.leaderIndex.CompareAndSwap(int32(leaderIndex), int32(next))
+	}
+	return err
 }
 
 // This line guarantees all method for RPCClient are implemented
 var _ ClientInterface = new(RPCClient)
 
-// Create an Surfstore RPC client
-func NewSurfstoreRPCClient(hostPort, baseDir string, blockSize int) RPCClient {
+// Create an Surfstore RPC client. hostPort may list the addresses of
+// several MetaStore replicas separated by CONFIG_DELIMITER.
+func NewSurfstoreRPCClient(hostPort, baseDir string, blockSize int) *RPCClient {
 
-	return RPCClient{
-		MetaStoreAddr: hostPort,
-		BaseDir:       baseDir,
-		BlockSize:     blockSize,
+	return &RPCClient{
+		MetaStoreAddrs:  strings.Split(hostPort, CONFIG_DELIMITER),
+		BaseDir:         baseDir,
+		BlockSize:       blockSize,
+		GetBlockTimeout: DEFAULT_GET_BLOCK_TIMEOUT,
+		conns:           &connPool{conns: map[string]*grpc.ClientConn{}},
 	}
 }
@@ -0,0 +1,203 @@
+package surfstore
+
+import (
+	context "context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// flakyMetaStore loses the answer to the first call of each kind, after
+// applying it
+type flakyMetaStore struct {
+	*MetaStore
+	mtx         sync.Mutex
+	updateCalls int
+	readCalls   int
+}
+
+func (m *flakyMetaStore) UpdateFile(ctx context.Context, fileMetaData *FileMetaData) (*Version, error) {
+	m.mtx.Lock()
+	m.updateCalls++
+	first := m.updateCalls == 1
+	m.mtx.Unlock()
+	version, err := m.MetaStore.UpdateFile(ctx, fileMetaData)
+	if first {
+		return nil, status.Error(codes.Unavailable, "connection lost")
+	}
+	return version, err
+}
+
+func (m *flakyMetaStore) GetFileInfoMap(ctx context.Context, empty *emptypb.Empty) (*FileInfoMap, error) {
+	m.mtx.Lock()
+	m.readCalls++
+	first := m.readCalls == 1
+	m.mtx.Unlock()
+	if first {
+		return nil, status.Error(codes.Unavailable, "connection lost")
+	}
+	return m.MetaStore.GetFileInfoMap(ctx, empty)
+}
+
+// A read whose answer was lost is retried, and so is a write: the retried
+// write is refused but the client finds its own update committed
+func TestCallMetaStoreRetries(t *testing.T) {
+	metaStore, err := NewMetaStore(nil, "", DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flaky := &flakyMetaStore{MetaStore: metaStore}
+	addr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterMetaStoreServer(server, flaky)
+	})
+	client := NewSurfstoreRPCClient(addr+CONFIG_DELIMITER+addr, "", 0)
+	defer client.Close()
+	ctx := context.Background()
+
+	var latestVersion int32
+	if err := client.UpdateFile(ctx, &FileMetaData{Filename: "f.txt", Version: 1, BlockHashList: []string{"h"}}, &latestVersion); err != nil {
+		t.Fatalf("update whose answer was lost: %v", err)
+	}
+	if latestVersion != 1 {
+		t.Fatalf("got version %d, want 1", latestVersion)
+	}
+	if flaky.updateCalls != 2 {
+		t.Fatalf("UpdateFile was sent %d times, want 2", flaky.updateCalls)
+	}
+
+	var fileInfoMap map[string]*FileMetaData
+	if err := client.GetFileInfoMap(ctx, &fileInfoMap); err != nil {
+		t.Fatal(err)
+	}
+	if fileInfoMap["f.txt"] == nil || fileInfoMap["f.txt"].Version != 1 {
+		t.Fatalf("got %v, want f.txt at version 1", fileInfoMap["f.txt"])
+	}
+
+	// someone else's update at the same version is still a conflict
+	err = client.UpdateFile(ctx, &FileMetaData{Filename: "f.txt", Version: 1, BlockHashList: []string{"other"}}, &latestVersion)
+	if !errors.Is(err, ERR_VERSION_MISMATCH) {
+		t.Fatalf("got %v, want a version mismatch", err)
+	}
+}
+
+// Calls made at once all move off a dead replica, and the leader they find
+// is kept by the client rather than by a copy of it
+func TestCallMetaStoreSharesLeader(t *testing.T) {
+	metaStore, err := NewMetaStore(nil, "", DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterMetaStoreServer(server, metaStore)
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := l.Addr().String()
+	l.Close()
+	client := NewSurfstoreRPCClient(deadAddr+CONFIG_DELIMITER+addr, "", 0)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var fileInfoMap map[string]*FileMetaData
+			errs <- client.GetFileInfoMap(context.Background(), &fileInfoMap)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := client.leaderIndex.Load(); got != 1 {
+		t.Fatalf("client points at replica %d, want 1", got)
+	}
+}
+
+// A client that was not made by NewSurfstoreRPCClient creates its one
+// connection pool safely when first used from several goroutines
+func TestZeroClientSharesPool(t *testing.T) {
+	addr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterBlockStoreServer(server, NewBlockStore())
+	})
+	client := &RPCClient{}
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	conns := make(chan *grpc.ClientConn, 8)
+	for i := 0; i < cap(conns); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := client.getConn(addr)
+			if err != nil {
+				t.Error(err)
+			}
+			conns <- conn
+		}()
+	}
+	wg.Wait()
+	close(conns)
+	first := <-conns
+	for conn := range conns {
+		if conn != first {
+			t.Fatal("concurrent calls dialed separate connections")
+		}
+	}
+}
+
+// breakingWatchMetaStore streams one change at a time, then breaks the
+// stream as a leader change would
+type breakingWatchMetaStore struct {
+	*MetaStore
+}
+
+func (m *breakingWatchMetaStore) WatchChanges(request *WatchRequest, stream MetaStore_WatchChangesServer) error {
+	if err := stream.Send(&FileChange{Sequence: request.AfterSequence + 1, Filename: "f.txt"}); err != nil {
+		return err
+	}
+	return status.Error(codes.Unavailable, "leader changed")
+}
+
+// A watch keeps resuming for as long as its stream keeps delivering
+// changes, however many times it breaks
+func TestWatchChangesOutlivesRetries(t *testing.T) {
+	metaStore, err := NewMetaStore(nil, "", DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterMetaStoreServer(server, &breakingWatchMetaStore{metaStore})
+	})
+	client := NewSurfstoreRPCClient(addr, "", 0)
+	defer client.Close()
+
+	want := int64(3 * METASTORE_RETRIES)
+	var last int64
+	err = client.WatchChanges(context.Background(), 0, func(change *FileChange) error {
+		if change.Sequence != last+1 {
+			t.Fatalf("got change %d after %d", change.Sequence, last)
+		}
+		last = change.Sequence
+		if last == want {
+			return errStopWatching
+		}
+		return nil
+	})
+	if !errors.Is(err, errStopWatching) {
+		t.Fatalf("gave up after %d changes: %v", last, err)
+	}
+}
@@ -0,0 +1,219 @@
+package surfstore
+
+import (
+	context "context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// runParallel runs task(ctx, i) for every i below n, at most parallelism
+// of them at a time. The first task to fail cancels the ctx the others
+// were given, no further tasks are started and its error is returned.
+func runParallel(ctx context.Context, parallelism int, n int, task func(ctx context.Context, i int) error) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	slots := make(chan struct{}, parallelism)
+	for i := 0; i < n; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := task(ctx, i); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	if firstErr == nil {
+		// cancelled by the caller rather than a failed task
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// blockBatch is a run of blocks moved to or from one block server over a
+// single stream
+type blockBatch struct {
+	blockStoreAddr string
+	hashes         []string
+}
+
+// splitIntoBatches cuts the hashes wanted from each block server into
+// batches of at most BLOCK_TRANSFER_BATCH_SIZE, so a server holding most of
+// a file's blocks still serves them over several streams at once
+func splitIntoBatches(hashesByServer map[string][]string) []blockBatch {
+	batches := []blockBatch{}
+	for blockStoreAddr, hashes := range hashesByServer {
+		for start := 0; start < len(hashes); start += BLOCK_TRANSFER_BATCH_SIZE {
+			end := start + BLOCK_TRANSFER_BATCH_SIZE
+			if end > len(hashes) {
+				end = len(hashes)
+			}
+			batches = append(batches, blockBatch{blockStoreAddr: blockStoreAddr, hashes: hashes[start:end]})
+		}
+	}
+	return batches
+}
+
+// fetchBlocks downloads the blocks in hashList and returns their data by
+// hash. Blocks are read from their first replica in batches, up to
+// parallelism of them at a time across all block servers. When a block
+// server cannot be reached, or sends no block within the client's
+// GetBlockTimeout, its blocks are fetched from their next replica instead. A batch fails as a whole when one of its blocks is missing, so
+// blocks that have no replica left are asked for on their own before the
+// download fails. Every block is re-hashed, a block that does not match its
+// hash is fetched from the next replica too, and fails the download with a
+// BlockCorruptionError when no replica is left.
+func fetchBlocks(ctx context.Context, client *RPCClient, parallelism int, hashList []string) (map[string][]byte, error) {
+	blockReplicas := make(map[string][]string)
+	err := client.GetBlockReplicas(ctx, hashList, &blockReplicas)
+	if err != nil {
+		return nil, err
+	}
+	hashToData := make(map[string][]byte)
+	// index into blockReplicas of the replica each pending block is read from
+	pending := make(map[string]int)
+	for _, hash := range hashList {
+		if hash != "-1" {
+			pending[hash] = 0
+		}
+	}
+	// blocks on their last replica whose batch failed, possibly because of
+	// another block in it, asked for in a batch of their own
+	alone := make(map[string]bool)
+	for len(pending) > 0 {
+		wantedFrom := make(map[string][]string)
+		aloneBatches := []blockBatch{}
+		for hash, replica := range pending {
+			replicas := blockReplicas[hash]
+			if replica >= len(replicas) {
+				return nil, fmt.Errorf("No block server holds block %s", hash)
+			}
+			if alone[hash] {
+				aloneBatches = append(aloneBatches, blockBatch{blockStoreAddr: replicas[replica], hashes: []string{hash}})
+				continue
+			}
+			wantedFrom[replicas[replica]] = append(wantedFrom[replicas[replica]], hash)
+		}
+		batches := append(splitIntoBatches(wantedFrom), aloneBatches...)
+		// every batch gets its own slot, failures are dealt with below
+		received := make([][]*Block, len(batches))
+		errs := make([]error, len(batches))
+		err := runParallel(ctx, parallelism, len(batches), func(ctx context.Context, i int) error {
+			errs[i] = client.GetBlocks(ctx, batches[i].hashes, batches[i].blockStoreAddr, &received[i])
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i, batch := range batches {
+			if errs[i] != nil {
+				if ctx.Err() != nil {
+					// no replica will do better
+					return nil, errs[i]
+				}
+				for _, hash := range batch.hashes {
+					if pending[hash]+1 >= len(blockReplicas[hash]) {
+						if len(batch.hashes) > 1 && status.Code(errs[i]) != codes.Unavailable && status.Code(errs[i]) != codes.DeadlineExceeded {
+							alone[hash] = true
+							continue
+						}
+						return nil, errs[i]
+					}
+					pending[hash]++
+				}
+				continue
+			}
+			for j, block := range received[i] {
+				hash := batch.hashes[j]
+				actualHash := GetBlockHashString(block.BlockData)
+				if actualHash != hash || int(block.BlockSize) != len(block.BlockData) {
+					if pending[hash]+1 >= len(blockReplicas[hash]) {
+						return nil, &BlockCorruptionError{Hash: hash, ActualHash: actualHash, BlockStoreAddr: batch.blockStoreAddr}
+					}
+					pending[hash]++
+					continue
+				}
+				hashToData[hash] = block.BlockData
+				delete(pending, hash)
+			}
+		}
+	}
+	return hashToData, nil
+}
+
+// addToBlockStore uploads the blocks of a file that its block servers do
+// not already have and returns how many bytes it sent. Each block server in
+// blockMap, every replica of a block is in it, is asked which of its hashes
+// are missing, then only those blocks are sent in batches. Up to
+// parallelism calls run at a time across all block servers.
+func addToBlockStore(ctx context.Context, client *RPCClient, parallelism int, fileMetaData *FileMetaData, hashToData map[string][]byte, blockMap map[string][]string) (int64, error) {
+	blockStoreAddrs := make([]string, 0, len(blockMap))
+	for blockStoreAddr, hashList := range blockMap {
+		for _, hash := range hashList {
+			if hash != "-1" && hash != "0" {
+				blockStoreAddrs = append(blockStoreAddrs, blockStoreAddr)
+				break
+			}
+		}
+	}
+	missing := make([][]string, len(blockStoreAddrs))
+	err := runParallel(ctx, parallelism, len(blockStoreAddrs), func(ctx context.Context, i int) error {
+		var blockHashes []string
+		for _, hash := range blockMap[blockStoreAddrs[i]] {
+			if hash != "-1" && hash != "0" {
+				blockHashes = append(blockHashes, hash)
+			}
+		}
+		return client.MissingBlocks(ctx, blockHashes, blockStoreAddrs[i], &missing[i])
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	missingFrom := make(map[string][]string)
+	var bytesUploaded int64
+	for i, blockStoreAddr := range blockStoreAddrs {
+		if len(missing[i]) == 0 {
+			continue
+		}
+		missingFrom[blockStoreAddr] = missing[i]
+		for _, hash := range missing[i] {
+			bytesUploaded += int64(len(hashToData[hash]))
+		}
+	}
+	batches := splitIntoBatches(missingFrom)
+	err = runParallel(ctx, parallelism, len(batches), func(ctx context.Context, i int) error {
+		blocks := make([]*Block, 0, len(batches[i].hashes))
+		for _, hash := range batches[i].hashes {
+			blockData := hashToData[hash]
+			blocks = append(blocks, &Block{BlockData: blockData, BlockSize: int32(len(blockData))})
+		}
+		var success bool
+		return client.PutBlocks(ctx, blocks, batches[i].blockStoreAddr, &success)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return bytesUploaded, nil
+}
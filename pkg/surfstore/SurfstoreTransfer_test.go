@@ -0,0 +1,350 @@
+package surfstore
+
+import (
+	context "context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	grpc "google.golang.org/grpc"
+)
+
+// corruptingBlockStore stores blocks but flips a byte of every block it
+// serves
+type corruptingBlockStore struct {
+	*BlockStore
+}
+
+type corruptingStream struct {
+	BlockStore_GetBlocksServer
+}
+
+func (s corruptingStream) Send(block *Block) error {
+	corrupted := append([]byte{}, block.BlockData...)
+	corrupted[0] ^= 0xFF
+	return s.BlockStore_GetBlocksServer.Send(&Block{BlockData: corrupted, BlockSize: block.BlockSize})
+}
+
+func (bs *corruptingBlockStore) GetBlocks(blockHashesIn *BlockHashes, stream BlockStore_GetBlocksServer) error {
+	return bs.BlockStore.GetBlocks(blockHashesIn, corruptingStream{stream})
+}
+
+// recordingBlockStore records the hash of every block uploaded to it
+type recordingBlockStore struct {
+	*BlockStore
+	mtx      sync.Mutex
+	uploaded []string
+}
+
+func (bs *recordingBlockStore) record(block *Block) {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+	bs.uploaded = append(bs.uploaded, GetBlockHashString(block.BlockData))
+}
+
+func (bs *recordingBlockStore) PutBlock(ctx context.Context, block *Block) (*Success, error) {
+	bs.record(block)
+	return bs.BlockStore.PutBlock(ctx, block)
+}
+
+type recordingStream struct {
+	BlockStore_PutBlocksServer
+	bs *recordingBlockStore
+}
+
+func (s recordingStream) Recv() (*Block, error) {
+	block, err := s.BlockStore_PutBlocksServer.Recv()
+	if err == nil {
+		s.bs.record(block)
+	}
+	return block, err
+}
+
+func (bs *recordingBlockStore) PutBlocks(stream BlockStore_PutBlocksServer) error {
+	return bs.BlockStore.PutBlocks(recordingStream{stream, bs})
+}
+
+// takeUploaded returns the hashes uploaded since it was last called
+func (bs *recordingBlockStore) takeUploaded() []string {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+	uploaded := bs.uploaded
+	bs.uploaded = nil
+	return uploaded
+}
+
+// tricklingBlockStore waits delay before sending each block
+type tricklingBlockStore struct {
+	*BlockStore
+	delay time.Duration
+}
+
+type tricklingStream struct {
+	BlockStore_GetBlocksServer
+	delay time.Duration
+}
+
+func (s tricklingStream) Send(block *Block) error {
+	time.Sleep(s.delay)
+	return s.BlockStore_GetBlocksServer.Send(block)
+}
+
+func (bs *tricklingBlockStore) GetBlocks(blockHashesIn *BlockHashes, stream BlockStore_GetBlocksServer) error {
+	return bs.BlockStore.GetBlocks(blockHashesIn, tricklingStream{stream, bs.delay})
+}
+
+// deadAddr returns an address nothing listens on
+func deadAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	return l.Addr().String()
+}
+
+// startReplicatedMetaStore serves a MetaStore that stores every block on
+// all of blockStoreAddrs
+func startReplicatedMetaStore(t *testing.T, blockStoreAddrs []string) *RPCClient {
+	t.Helper()
+	metaStore, err := NewMetaStore(blockStoreAddrs, "", DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring := NewWeightedConsistentHashRing(blockStoreAddrs, nil, 16)
+	ring.ReplicationFactor = len(blockStoreAddrs)
+	metaStore.SetConsistentHashRing(ring)
+	addr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterMetaStoreServer(server, metaStore)
+	})
+	client := NewSurfstoreRPCClient(addr, "", 0)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// A download falls back to the next replica of a block when a BlockStore
+// is down, stalls or serves a corrupt block
+func TestFetchBlocksFallsBack(t *testing.T) {
+	down := deadAddr(t)
+	corrupt := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterBlockStoreServer(server, &corruptingBlockStore{NewBlockStore()})
+	})
+	stalled := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterBlockStoreServer(server, &stallingBlockStore{NewBlockStore()})
+	})
+	healthy := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterBlockStoreServer(server, NewBlockStore())
+	})
+	client := startReplicatedMetaStore(t, []string{down, corrupt, stalled, healthy})
+	client.GetBlockTimeout = 200 * time.Millisecond
+
+	data := []string{}
+	for i := 0; i < 50; i++ {
+		data = append(data, "block "+strconv.Itoa(i))
+	}
+	hashes := putTestBlocks(t, client, corrupt, data...)
+	putTestBlocks(t, client, healthy, data...)
+
+	var blockReplicas map[string][]string
+	if err := client.GetBlockReplicas(context.Background(), hashes, &blockReplicas); err != nil {
+		t.Fatal(err)
+	}
+	firstReplicas := map[string]bool{}
+	for _, hash := range hashes {
+		firstReplicas[blockReplicas[hash][0]] = true
+	}
+	if !firstReplicas[down] || !firstReplicas[corrupt] || !firstReplicas[stalled] {
+		t.Fatal("no block is read from the down, the corrupting or the stalled BlockStore first")
+	}
+
+	hashToData, err := fetchBlocks(context.Background(), client, 4, hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, hash := range hashes {
+		if string(hashToData[hash]) != data[i] {
+			t.Fatalf("block %d is %q, want %q", i, hashToData[hash], data[i])
+		}
+	}
+}
+
+// When every replica serves a corrupt block the download fails with a
+// BlockCorruptionError naming the last of them
+func TestFetchBlocksAllReplicasCorrupt(t *testing.T) {
+	corrupt := []string{}
+	for i := 0; i < 2; i++ {
+		corrupt = append(corrupt, startTestServer(t, func(server *grpc.Server, _ string) {
+			RegisterBlockStoreServer(server, &corruptingBlockStore{NewBlockStore()})
+		}))
+	}
+	client := startReplicatedMetaStore(t, corrupt)
+	hashes := putTestBlocks(t, client, corrupt[0], "only block")
+	putTestBlocks(t, client, corrupt[1], "only block")
+	var blockReplicas map[string][]string
+	if err := client.GetBlockReplicas(context.Background(), hashes, &blockReplicas); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := fetchBlocks(context.Background(), client, 4, hashes)
+	var corruption *BlockCorruptionError
+	if !errors.As(err, &corruption) {
+		t.Fatalf("got %v, want a BlockCorruptionError", err)
+	}
+	if last := blockReplicas[hashes[0]][1]; corruption.Hash != hashes[0] || corruption.BlockStoreAddr != last {
+		t.Fatalf("got %v, want block %s from %s", corruption, hashes[0], last)
+	}
+}
+
+// A block whose only replica holds it is read even when another block in
+// the same batch has not been migrated there yet
+func TestFetchBlocksDuringMigration(t *testing.T) {
+	addrs, _ := startMemoryBlockStores(t, 3)
+	metaStore, err := NewMetaStore(addrs[:2], "", DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// addrs[2] was just removed, its blocks are still there
+	previous := NewWeightedConsistentHashRing(addrs, nil, 16)
+	ring := NewWeightedConsistentHashRing(addrs[:2], nil, 16)
+	metaStore.SetConsistentHashRing(ring)
+	metaStore.migrator.restore([]*ConsistentHashRing{previous}, ring)
+	metaAddr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterMetaStoreServer(server, metaStore)
+	})
+	client := NewSurfstoreRPCClient(metaAddr, "", 0)
+	defer client.Close()
+
+	data := []string{}
+	for i := 0; i < 50; i++ {
+		data = append(data, "block "+strconv.Itoa(i))
+	}
+	hashes := []string{}
+	for _, d := range data {
+		hash, _ := testBlock(d)
+		hashes = append(hashes, putTestBlocks(t, client, previous.GetResponsibleServers(hash)[0], d)...)
+	}
+	var blockReplicas map[string][]string
+	if err := client.GetBlockReplicas(context.Background(), hashes, &blockReplicas); err != nil {
+		t.Fatal(err)
+	}
+	// first replicas holding both a block that stays and one that moves there
+	stays, moves := map[string]bool{}, map[string]bool{}
+	for _, hash := range hashes {
+		if len(blockReplicas[hash]) == 1 {
+			stays[blockReplicas[hash][0]] = true
+		} else {
+			moves[blockReplicas[hash][0]] = true
+		}
+	}
+	if !(stays[addrs[0]] && moves[addrs[0]]) && !(stays[addrs[1]] && moves[addrs[1]]) {
+		t.Fatal("no BlockStore is asked for a block it holds and one it lacks at once")
+	}
+
+	hashToData, err := fetchBlocks(context.Background(), client, 4, hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, hash := range hashes {
+		if string(hashToData[hash]) != data[i] {
+			t.Fatalf("block %d is %q, want %q", i, hashToData[hash], data[i])
+		}
+	}
+}
+
+// Only the blocks a BlockStore lacks are uploaded, blocks shared with an
+// earlier version or another file are not sent again
+func TestSyncUploadsOnlyMissingBlocks(t *testing.T) {
+	blockStore := &recordingBlockStore{BlockStore: NewBlockStore()}
+	addr := startTestServer(t, func(server *grpc.Server, addr string) {
+		metaStore, err := NewMetaStore([]string{addr}, "", DEFAULT_FILE_HISTORY_SIZE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		RegisterMetaStoreServer(server, metaStore)
+		RegisterBlockStoreServer(server, blockStore)
+	})
+	baseDir := t.TempDir()
+	writeTestFile(t, baseDir, "f.txt", "aaaabbbb")
+	syncDir(t, addr, baseDir)
+	if uploaded := blockStore.takeUploaded(); len(uploaded) != 2 {
+		t.Fatalf("uploaded %d blocks for a new two block file, want 2", len(uploaded))
+	}
+
+	writeTestFile(t, baseDir, "f.txt", "aaaabbbbcccc")
+	writeTestFile(t, baseDir, "g.txt", "bbbbaaaadddd")
+	syncDir(t, addr, baseDir)
+	uploaded := blockStore.takeUploaded()
+	sort.Strings(uploaded)
+	want := []string{GetBlockHashString([]byte("cccc")), GetBlockHashString([]byte("dddd"))}
+	sort.Strings(want)
+	if len(uploaded) != len(want) || uploaded[0] != want[0] || uploaded[1] != want[1] {
+		t.Fatalf("uploaded %v, want only the new blocks %v", uploaded, want)
+	}
+}
+
+// A download gives up on a BlockStore that sends no block within the
+// block timeout and reads from the next replica instead
+func TestFetchBlocksStalledReplica(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+	stalled := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterBlockStoreServer(server, &stallingBlockStore{NewBlockStore()})
+	})
+	healthy := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterBlockStoreServer(server, NewBlockStore())
+	})
+	client := startReplicatedMetaStore(t, []string{stalled, healthy})
+	client.GetBlockTimeout = timeout
+
+	data := []string{}
+	for i := 0; i < 50; i++ {
+		data = append(data, "block "+strconv.Itoa(i))
+	}
+	hashes := putTestBlocks(t, client, healthy, data...)
+	var blockReplicas map[string][]string
+	if err := client.GetBlockReplicas(context.Background(), hashes, &blockReplicas); err != nil {
+		t.Fatal(err)
+	}
+	stalledFirst := false
+	for _, hash := range hashes {
+		stalledFirst = stalledFirst || blockReplicas[hash][0] == stalled
+	}
+	if !stalledFirst {
+		t.Fatal("no block is read from the stalled BlockStore first")
+	}
+
+	start := time.Now()
+	hashToData, err := fetchBlocks(context.Background(), client, 4, hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*timeout {
+		t.Fatalf("the download took %v with a block timeout of %v", elapsed, timeout)
+	}
+	for i, hash := range hashes {
+		if string(hashToData[hash]) != data[i] {
+			t.Fatalf("block %d is %q, want %q", i, hashToData[hash], data[i])
+		}
+	}
+}
+
+// The block timeout bounds the wait for each block, not the whole stream
+func TestGetBlocksSlowStream(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+	addr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterBlockStoreServer(server, &tricklingBlockStore{NewBlockStore(), timeout / 2})
+	})
+	client := NewSurfstoreRPCClient("", "", 0)
+	defer client.Close()
+	client.GetBlockTimeout = timeout
+	hashes := putTestBlocks(t, client, addr, "a", "b", "c", "d", "e", "f")
+
+	var blocks []*Block
+	if err := client.GetBlocks(context.Background(), hashes, addr, &blocks); err != nil {
+		t.Fatalf("a stream sending a block every %v failed: %v", timeout/2, err)
+	}
+}
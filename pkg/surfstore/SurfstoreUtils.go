@@ -1,97 +1,179 @@
 package surfstore
 
 import (
+	context "context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-// Implement the logic for a client syncing with the server here.
-func ClientSync(client RPCClient) {
+// BlockCorruptionError is returned when a block fetched from a BlockStore
+// does not hash to the hash it was requested by.
+type BlockCorruptionError struct {
+	Hash           string
+	ActualHash     string
+	BlockStoreAddr string
+}
+
+func (e *BlockCorruptionError) Error() string {
+	return fmt.Sprintf("block %s from %s is corrupt: data hashes to %s", e.Hash, e.BlockStoreAddr, e.ActualHash)
+}
+
+// SyncError is returned by Sync when one of its steps fails. Op names the
+// step and FileName the file it failed on, if any, Err is the cause.
+type SyncError struct {
+	Op       string
+	FileName string
+	Err      error
+}
+
+func (e *SyncError) Error() string {
+	if e.FileName == "" {
+		return fmt.Sprintf("sync: %s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("sync: %s %s: %v", e.Op, e.FileName, e.Err)
+}
+
+func (e *SyncError) Unwrap() error {
+	return e.Err
+}
+
+// SyncOptions says what Sync syncs and how
+type SyncOptions struct {
+	// Client to sync through, its BaseDir is the directory that is synced
+	// and its BlockSize and Chunker decide how files are cut into blocks
+	Client *RPCClient
+	// Number of block transfers run at once, DEFAULT_SYNC_PARALLELISM if 0
+	Parallelism int
+}
+
+// SyncConflict is a file whose local edits lost a race with a remote update
+type SyncConflict struct {
+	FileName string
+	// Name the local edits were saved and uploaded under
+	ConflictCopy string
+}
+
+// SyncReport describes what a sync changed
+type SyncReport struct {
+	// Files whose new contents were uploaded, conflict copies included
+	Uploaded []string
+	// Files whose remote contents were written to the base directory
+	Downloaded []string
+	// Files deleted, locally deletions pushed to the MetaStore and remote
+	// deletions applied to the base directory
+	Deleted []string
+	// Files edited both locally and remotely
+	Conflicts []SyncConflict
+	// Bytes of block data sent to and received from the BlockStores
+	BytesUploaded   int64
+	BytesDownloaded int64
+}
+
+// Sync brings the base directory of opts.Client and the MetaStore in sync:
+// local changes are uploaded, remote changes are downloaded and index.db
+// records the result. Errors are returned as a *SyncError, index.db is only
+// written once everything else succeeded so a failed sync is simply
+// repeated by the next one.
+//
+// Cancelling ctx stops the sync at the next RPC or file, in-flight
+// transfers included. Blocks are uploaded before the file is committed and
+// downloads are written to a temp file that is renamed into place, so a
+// cancelled or crashed sync leaves no file half written and no committed
+// file without its blocks.
+func Sync(ctx context.Context, opts SyncOptions) (*SyncReport, error) {
+	report, err := syncBaseDir(ctx, opts)
+	var syncErr *SyncError
+	if ctx.Err() != nil && errors.As(err, &syncErr) && isCancellation(syncErr.Err) {
+		// the step that failed only saw a cancelled RPC
+		syncErr.Err = ctx.Err()
+	}
+	return report, err
+}
+
+// ClientSync syncs the base directory of client, exiting the program if the
+// sync fails.
+//
+// Deprecated: use Sync, which can be cancelled and returns what it changed
+// and why it failed.
+func ClientSync(client *RPCClient) {
+	if _, err := Sync(context.Background(), SyncOptions{Client: client}); err != nil {
+		log.Fatal("Error syncing: ", err)
+	}
+}
+
+// isCancellation reports whether err is a cancelled or timed out context,
+// or an RPC that failed because of one
+func isCancellation(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	code := status.Code(err)
+	return code == codes.Canceled || code == codes.DeadlineExceeded
+}
 
+func syncBaseDir(ctx context.Context, opts SyncOptions) (*SyncReport, error) {
+	client := opts.Client
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = DEFAULT_SYNC_PARALLELISM
+	}
 	baseDir := client.BaseDir
-	blockSize := client.BlockSize
+	chunker := client.Chunker
+	if chunker == nil {
+		chunker = &FixedSizeChunker{BlockSize: client.BlockSize}
+	}
 	hashToData := make(map[string][]byte)
+	report := &SyncReport{}
 
-	// open the base directory
-	directory, err := os.Open(baseDir)
-	if os.IsNotExist(err) {
-		log.Fatal("Base directory does not exist")
+	// make sure the base directory exists
+	if _, err := os.Stat(baseDir); err != nil {
+		return nil, &SyncError{Op: "open base directory", Err: err}
+	}
+	if err := removeStaleDownloads(baseDir); err != nil {
+		return nil, &SyncError{Op: "remove stale downloads in base directory", Err: err}
 	}
-	defer directory.Close()
-	//process all files in the base directory
+	//process all files in the base directory and its subdirectories
 	localDirectory := make(map[string][]string)
-	files, err := directory.Readdir(-1)
+	fileNames, err := listLocalFiles(baseDir)
 	if err != nil {
-		log.Fatal("Error reading base directory")
-	}
-	for _, file := range files {
-		// open the file
-		fileName := file.Name()
-		ok := validateFileName(fileName)
-		if !ok {
-			// fmt.Println(fileName)
-			// fmt.Println("Invalid file name")
-			continue
-		}
-		localDirectory[fileName] = []string{}
-		filePath := baseDir + "/" + file.Name()
-		file, err := os.Open(filePath)
-		if err != nil {
-			log.Fatal("Error opening file")
+		return nil, &SyncError{Op: "read base directory", Err: err}
+	}
+	for _, fileName := range fileNames {
+		if err := ctx.Err(); err != nil {
+			return nil, &SyncError{Op: "read", FileName: fileName, Err: err}
 		}
-		defer file.Close()
-		// read the file
-		var hashList []string
-		// if the file is empty, add a -1
-		stat, err := file.Stat()
+		hashList, err := hashLocalFile(baseDir+"/"+fileName, chunker, hashToData)
 		if err != nil {
-			log.Fatal("Error getting file stats")
-		}
-		if stat.Size() == 0 {
-			// fmt.Println("EMPTY FILE: " + fileName)
-			hashList = append(hashList, "-1")
-			localDirectory[fileName] = hashList
-			continue
-		}
-		for {
-			fileData := make([]byte, blockSize)
-			n, err := file.Read(fileData)
-			if err != nil {
-				if err == io.EOF {
-					break
-				}
-				log.Fatal("Error reading file:", err)
-			}
-			// Use only the read data
-			fileData = fileData[:n]
-
-			// Hash the block
-			hash := GetBlockHashString(fileData)
-			hashList = append(hashList, hash)
-			localDirectory[fileName] = hashList
-			// fmt.Println("FILE DATA: ", fileData)
-			// fmt.Println("HASH: ", hash)
-			hashToData[hash] = fileData
+			return nil, &SyncError{Op: "read", FileName: fileName, Err: err}
 		}
-
+		localDirectory[fileName] = hashList
 	}
 	// load the meta file as a local map (localIndex)
 	localIndex, err := LoadMetaFromMetaFile(baseDir)
 	if err != nil {
-		log.Fatal("Error loading meta file")
+		return nil, &SyncError{Op: "load index", Err: err}
 	}
-	// fmt.Println("LOCAL INDEX LOADED FROM INDEX FILE")
-	PrintMetaMap(localIndex)
 
 	// compare the local index with the local directory
 	updatedLocalIndex := make(map[string]*FileMetaData)
+	// files created or edited locally since the last sync
+	locallyModified := make(map[string]bool)
 	for fileName, hashList := range localDirectory {
 		// if the file is not in the index file, add it
 		if _, ok := localIndex[fileName]; !ok {
 			updatedLocalIndex[fileName] = &FileMetaData{Filename: fileName, Version: 1, BlockHashList: hashList}
+			locallyModified[fileName] = true
 		} else { // file is in the index file, check the hash list
 			var newHashList []string
 			localIndexHashList := localIndex[fileName].BlockHashList
@@ -113,6 +195,7 @@ func ClientSync(client RPCClient) {
 			}
 			if changed { // if the hash list is different, update the index file with new hash and version
 				updatedLocalIndex[fileName] = &FileMetaData{Filename: fileName, Version: localIndex[fileName].Version + 1, BlockHashList: newHashList}
+				locallyModified[fileName] = true
 			} else { // if the hash list is the same, keep the index file, no version change
 				updatedLocalIndex[fileName] = localIndex[fileName]
 			}
@@ -135,66 +218,60 @@ func ClientSync(client RPCClient) {
 			}
 		}
 	}
-	// print the updated local index
-	fmt.Println("UPDATED LOCAL INDEX AFTER COMPARISON WITH LOCAL DIRECTORY")
-	PrintMetaMap(updatedLocalIndex)
 
 	//load the remote index from the server
-	hostPort := client.MetaStoreAddr
-	rpcClient := NewSurfstoreRPCClient(hostPort, baseDir, blockSize)
-	remoteIndex := make(map[string]*FileMetaData)
-	err = rpcClient.GetFileInfoMap(&remoteIndex)
+	rpcClient := client
+	remoteIndex, cursor, err := loadRemoteIndex(ctx, rpcClient, localIndex)
 	if err != nil {
-		log.Fatal("Error getting remote index")
+		return nil, &SyncError{Op: "get remote index", Err: err}
 	}
-	fmt.Println("REMOTE INDEX LOADED FROM SERVER")
-	PrintMetaMap(remoteIndex)
 	remoteBlockStoreAddrs := []string{}
 	//load all the block store address
-	err = rpcClient.GetBlockStoreAddrs(&remoteBlockStoreAddrs)
+	err = rpcClient.GetBlockStoreAddrs(ctx, &remoteBlockStoreAddrs)
 	if err != nil {
-		log.Fatal("Error getting block store address")
-	}
-
-	for _, blockStoreAddr := range remoteBlockStoreAddrs {
-		fmt.Println("Block Store Address: ", blockStoreAddr)
+		return nil, &SyncError{Op: "get block store addresses", Err: err}
 	}
+	log.Println("Block Store Addresses:", remoteBlockStoreAddrs)
 
 	//compare the remote index with the updated local index
 	finalMetaMap := make(map[string]*FileMetaData)
 	for fileName, localFileMetaData := range updatedLocalIndex {
+		if err := ctx.Err(); err != nil {
+			return nil, &SyncError{Op: "continue with", FileName: fileName, Err: err}
+		}
 		blockMap := make(map[string][]string)
-		err = rpcClient.GetBlockStoreMap(localFileMetaData.BlockHashList, &blockMap)
+		err = rpcClient.GetBlockStoreMap(ctx, localFileMetaData.BlockHashList, &blockMap)
 		if err != nil {
-			log.Fatal("Error getting block store map")
+			return nil, &SyncError{Op: "get block store map", FileName: fileName, Err: err}
 		}
 		// file in local index but not remote index, add it to the remote index
 		if _, ok := remoteIndex[fileName]; !ok {
 			// fmt.Println("FILE: " + fileName + " IN LOCAL INDEX BUT NOT IN REMOTE INDEX")
-			err = rpcClient.UpdateFile(localFileMetaData, &localFileMetaData.Version)
-			if err != nil {
-				log.Fatal("Error updating file")
+			if err := uploadFile(ctx, rpcClient, parallelism, localFileMetaData, hashToData, blockMap, report); err != nil {
+				return nil, err
 			}
-			addToBlockStore(rpcClient, localFileMetaData, remoteBlockStoreAddrs, hashToData, blockMap)
 			finalMetaMap[fileName] = localFileMetaData
 
 		} else { // file in both local and remote index, compare the version and hash list and update as necessary
 			remoteFileMetaData := remoteIndex[fileName]
 			// fmt.Println("FILE " + fileName + " IN BOTH LOCAL AND REMOTE INDEX")
+			if locallyModified[fileName] && localFileMetaData.Version <= remoteFileMetaData.Version &&
+				!sameList(localFileMetaData.BlockHashList, remoteFileMetaData.BlockHashList) {
+				// someone else pushed first, keep the local edits as a conflict copy
+				// before the remote version replaces the file below
+				conflictMetaData, err := saveConflictCopy(ctx, rpcClient, parallelism, localFileMetaData, hashToData, remoteIndex, report)
+				if err != nil {
+					return nil, err
+				}
+				finalMetaMap[conflictMetaData.Filename] = conflictMetaData
+			}
 			if localFileMetaData.Version < remoteFileMetaData.Version {
 				filePath := baseDir + "/" + fileName
 				// fmt.Println("LOCAL FILE " + fileName + " IS OUT OF DATE")
-				if remoteFileMetaData.BlockHashList[0] == "0" {
-					// fmt.Println("FILE HAS BEEN DELETED" + fileName)
-					finalMetaMap[fileName] = remoteFileMetaData
-					err := os.Remove(filePath)
-					if err != nil {
-						log.Fatal("Error deleting file")
-					}
-					continue
-				}
 				// edit the file in the base directory to match the remote file
-				editFile(filePath, remoteFileMetaData, rpcClient)
+				if err := editFile(ctx, rpcClient, parallelism, filePath, remoteFileMetaData, report); err != nil {
+					return nil, err
+				}
 				finalMetaMap[fileName] = remoteFileMetaData
 			} else if localFileMetaData.Version == remoteFileMetaData.Version { //check hash list for differences this means someone else has pushed first
 				// fmt.Println("LOCAL FILE " + fileName + " HAS SAME VERSION AS REMOTE FILE, CHECKING HASH LIST")
@@ -206,17 +283,17 @@ func ClientSync(client RPCClient) {
 				} else {
 					// edit the file in the base directory to match the remote file
 					filePath := baseDir + "/" + fileName
-					editFile(filePath, remoteFileMetaData, rpcClient)
+					if err := editFile(ctx, rpcClient, parallelism, filePath, remoteFileMetaData, report); err != nil {
+						return nil, err
+					}
 					finalMetaMap[fileName] = remoteFileMetaData
 				}
 
 			} else if localFileMetaData.Version == remoteFileMetaData.Version+1 { //update the file in the remote index (garbage collection doesnt occur )
 				// fmt.Println("LOCAL FILE " + fileName + " IS VERSION AHEAD OF REMOTE FILE, UPDATING REMOTE FILE")
-				err = rpcClient.UpdateFile(localFileMetaData, &localFileMetaData.Version)
-				if err != nil {
-					log.Fatal("Error updating file")
+				if err := uploadFile(ctx, rpcClient, parallelism, localFileMetaData, hashToData, blockMap, report); err != nil {
+					return nil, err
 				}
-				addToBlockStore(rpcClient, localFileMetaData, remoteBlockStoreAddrs, hashToData, blockMap)
 				finalMetaMap[fileName] = localFileMetaData
 			} else { //invalid version number
 				// fmt.Println("INVALID VERSION NUMBER")
@@ -230,7 +307,14 @@ func ClientSync(client RPCClient) {
 	for _, key := range remoteIndexkeys {
 		if !contains(localUpdatedIndexkeys, key) {
 			// fmt.Println("REMOTE INDEX FILE: " + key + " NOT IN LOCAL INDEX")
+			if err := ctx.Err(); err != nil {
+				return nil, &SyncError{Op: "continue with", FileName: key, Err: err}
+			}
 			remoteFileMetaData := remoteIndex[key]
+			if !validateFileName(key) {
+				// never write outside of the base directory
+				continue
+			}
 			if remoteFileMetaData.BlockHashList[0] == "0" {
 				// fmt.Println("FILE HAS BEEN DELETED" + key)
 				finalMetaMap[key] = remoteFileMetaData
@@ -238,25 +322,9 @@ func ClientSync(client RPCClient) {
 			}
 			// reconstitute the file in the base directory
 			filePath := baseDir + "/" + key
-			file, err := os.Create(filePath)
-			if err != nil {
-				log.Fatal("Error creating file")
+			if err := editFile(ctx, rpcClient, parallelism, filePath, remoteFileMetaData, report); err != nil {
+				return nil, err
 			}
-			defer file.Close()
-			writeToFile(remoteFileMetaData.BlockHashList, file, rpcClient)
-			// for _, hash := range remoteFileMetaData.BlockHashList {
-			// 	var block Block
-			// 	err := rpcClient.GetBlock(hash, remoteBlockStoreAddr, &block)
-			// 	if err != nil {
-			// 		log.Fatal("Error getting block" + err.Error())
-			// 	}
-			// 	// add the block to the block list and write to the file
-			// 	blockData := block.BlockData
-			// 	_, err = file.Write(blockData)
-			// 	if err != nil {
-			// 		log.Fatal("Error writing block to file")
-			// 	}
-			// }
 			// add the file to the local index
 			finalMetaMap[key] = remoteFileMetaData
 		}
@@ -265,19 +333,178 @@ func ClientSync(client RPCClient) {
 	//write all changes to index.db
 	err = WriteMetaFile(finalMetaMap, baseDir)
 	if err != nil {
-		log.Fatal("Error writing meta file")
+		return nil, &SyncError{Op: "write index", Err: err}
+	}
+	// a remote file index.db does not record is never in the changes
+	// since this cursor, have the next sync fetch the full remote index
+	for fileName := range remoteIndex {
+		if _, ok := finalMetaMap[fileName]; !ok && validateFileName(fileName) {
+			cursor = 0
+			break
+		}
+	}
+	// only after index.db, an older cursor just fetches some changes again
+	err = writeCursorFile(baseDir, cursor)
+	if err != nil {
+		return nil, &SyncError{Op: "write cursor", Err: err}
+	}
+	return report, nil
+}
+
+// hashLocalFile cuts a local file into blocks, keeping their data in
+// hashToData, and returns its block hash list
+func hashLocalFile(filePath string, chunker Chunker, hashToData map[string][]byte) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	// if the file is empty, add a -1
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if stat.Size() == 0 {
+		return []string{EMPTYFILE_HASHVALUE}, nil
 	}
-	// fmt.Println("INDEX.DB updated")
+	var hashList []string
+	err = chunker.Chunk(file, func(fileData []byte) {
+		// Hash the block
+		hash := GetBlockHashString(fileData)
+		hashList = append(hashList, hash)
+		hashToData[hash] = fileData
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashList, nil
+}
 
+// uploadFile uploads the blocks of a new local version of a file and then
+// commits it to the MetaStore, so no client ever sees the version before
+// its blocks. A file another client updated first fails with
+// ERR_VERSION_MISMATCH.
+func uploadFile(ctx context.Context, client *RPCClient, parallelism int, fileMetaData *FileMetaData, hashToData map[string][]byte, blockMap map[string][]string, report *SyncReport) error {
+	bytesUploaded, err := addToBlockStore(ctx, client, parallelism, fileMetaData, hashToData, blockMap)
+	if err != nil {
+		return &SyncError{Op: "upload", FileName: fileMetaData.Filename, Err: err}
+	}
+	err = client.UpdateFile(ctx, fileMetaData, &fileMetaData.Version)
+	if err != nil {
+		return &SyncError{Op: "update", FileName: fileMetaData.Filename, Err: err}
+	}
+	if fileMetaData.BlockHashList[0] == TOMBSTONE_HASHVALUE {
+		report.Deleted = append(report.Deleted, fileMetaData.Filename)
+		return nil
+	}
+	report.Uploaded = append(report.Uploaded, fileMetaData.Filename)
+	report.BytesUploaded += bytesUploaded
+	return nil
 }
 
+// loadRemoteIndex returns the remote index and the change cursor it is
+// current as of. After a previous sync only the changes since its cursor
+// are fetched, the files that did not change remotely are as recorded in
+// localIndex.
+func loadRemoteIndex(ctx context.Context, client *RPCClient, localIndex map[string]*FileMetaData) (map[string]*FileMetaData, int64, error) {
+	cursor, err := readCursorFile(client.BaseDir)
+	if err != nil || len(localIndex) == 0 {
+		// nothing to apply changes to
+		cursor = 0
+	}
+	var changes FileInfoChanges
+	err = client.GetChangesSince(ctx, cursor, &changes)
+	if status.Code(err) == codes.Unimplemented {
+		remoteIndex := make(map[string]*FileMetaData)
+		err = client.GetFileInfoMap(ctx, &remoteIndex)
+		return remoteIndex, 0, err
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if changes.Full {
+		return changes.FileInfoMap, changes.Cursor, nil
+	}
+	remoteIndex := make(map[string]*FileMetaData)
+	for fileName, fileMetaData := range localIndex {
+		remoteIndex[fileName] = fileMetaData
+	}
+	for fileName, fileMetaData := range changes.FileInfoMap {
+		remoteIndex[fileName] = fileMetaData
+	}
+	return remoteIndex, changes.Cursor, nil
+}
+
+// readCursorFile returns the cursor saved by the last sync, 0 if there is
+// none
+func readCursorFile(baseDir string) (int64, error) {
+	data, err := os.ReadFile(ConcatPath(baseDir, CURSOR_FILENAME))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func writeCursorFile(baseDir string, cursor int64) error {
+	return writeFileAtomic(ConcatPath(baseDir, CURSOR_FILENAME), []byte(strconv.FormatInt(cursor, 10)+"\n"))
+}
+
+// validateFileName checks a filename, the path of a file relative to the
+// base directory with "/" separators, can be synced and stays inside the
+// base directory
 func validateFileName(fileName string) bool {
-	if fileName == DEFAULT_META_FILENAME || strings.Contains(fileName, ",") || strings.Contains(fileName, "/") {
+	if fileName == DEFAULT_META_FILENAME || fileName == "" || strings.Contains(fileName, ",") || strings.Contains(fileName, "\\") {
 		return false
 	}
+	for _, part := range strings.Split(fileName, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
 	return true
 }
 
+// listLocalFiles returns the relative path of every file under baseDir
+func listLocalFiles(baseDir string) ([]string, error) {
+	fileNames := []string{}
+	err := filepath.WalkDir(baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), CLIENT_FILE_PREFIX) {
+			return nil
+		}
+		relPath, err := filepath.Rel(baseDir, path)
+		if err != nil {
+			return err
+		}
+		fileName := filepath.ToSlash(relPath)
+		if validateFileName(fileName) {
+			fileNames = append(fileNames, fileName)
+		}
+		return nil
+	})
+	return fileNames, err
+}
+
+// removeEmptyParents removes the directories above filePath that a remote
+// delete left empty, stopping at baseDir
+func removeEmptyParents(baseDir string, filePath string) {
+	base := filepath.Clean(baseDir)
+	for dir := filepath.Dir(filePath); dir != base && strings.HasPrefix(dir, base); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			// not empty
+			return
+		}
+	}
+}
+
 func getDirectoryKeys(m map[string][]string) []string {
 	keys := make([]string, len(m))
 	i := 0
@@ -306,88 +533,191 @@ func contains(s []string, e string) bool {
 	return false
 }
 
-func writeToFile(hashList []string, file *os.File, client RPCClient) {
-	blockMap := make(map[string][]string)
-	err := client.GetBlockStoreMap(hashList, &blockMap)
-	if err != nil {
-		log.Fatal("Error getting block store map")
-	}
+// writeToFile writes the blocks of hashList to file in order and returns
+// how many bytes it wrote
+func writeToFile(hashList []string, hashToData map[string][]byte, file *os.File) (int64, error) {
+	var bytesWritten int64
 	for _, hash := range hashList {
-		for blockStoreAddr, hashList := range blockMap {
-			if contains(hashList, hash) {
-				if hash != "-1" {
-					var block Block
-					err := client.GetBlock(hash, blockStoreAddr, &block)
-					if err != nil {
-						log.Fatal("Error getting block" + err.Error())
-					}
-					// add the block to the block list and write to the file
-					blockData := block.BlockData
-					_, err = file.Write(blockData)
-					if err != nil {
-						log.Fatal("Error writing block to file")
-					}
-				}
+		if hash == "-1" {
+			continue
+		}
+		_, err := file.Write(hashToData[hash])
+		if err != nil {
+			return bytesWritten, err
+		}
+		bytesWritten += int64(len(hashToData[hash]))
+	}
+	return bytesWritten, nil
+}
 
-			}
+// verifyFile reads file back from the start and checks that it is made of
+// exactly the blocks of hashList
+func verifyFile(hashList []string, hashToData map[string][]byte, file *os.File) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	for i, hash := range hashList {
+		if hash == "-1" {
+			continue
+		}
+		blockData := make([]byte, len(hashToData[hash]))
+		if _, err := io.ReadFull(file, blockData); err != nil {
+			return fmt.Errorf("block %d is short on disk: %v", i, err)
 		}
+		if GetBlockHashString(blockData) != hash {
+			return fmt.Errorf("block %d does not match %s on disk", i, hash)
+		}
+	}
+	if n, _ := file.Read(make([]byte, 1)); n != 0 {
+		return errors.New("file is longer than its blocks on disk")
 	}
+	return nil
+}
 
+// materializeFile writes the blocks of hashList to a temp file next to
+// filePath, fsyncs and verifies it, then renames it over filePath. filePath is not
+// touched before the rename, so a failed or interrupted download leaves the
+// previous file in place. The temp file of a crashed client is removed by
+// removeStaleDownloads on the next sync.
+func materializeFile(filePath string, hashList []string, hashToData map[string][]byte) (int64, error) {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filePath); err == nil && info.Mode().IsRegular() {
+		mode = info.Mode().Perm()
+	}
+	// next to filePath, so syncing its directory makes the rename durable
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), DOWNLOAD_FILE_PREFIX+"*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmpFile.Name())
+	bytesWritten, err := writeToFile(hashList, hashToData, tmpFile)
+	if err == nil {
+		err = tmpFile.Chmod(mode)
+	}
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if err == nil {
+		err = verifyFile(hashList, hashToData, tmpFile)
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmpFile.Name(), filePath); err != nil {
+		return 0, err
+	}
+	return bytesWritten, syncFile(filepath.Dir(filePath))
 }
 
-func addToBlockStore(client RPCClient, fileMetaData *FileMetaData, blockStoreAddrs []string, hashToData map[string][]byte, blockMap map[string][]string) {
-	fmt.Println("ADDING FILE TO BLOCK STORE")
-	var success bool
-	for blockStoreAddr, hashList := range blockMap {
-		for _, hash := range hashList {
-			if hash != "-1" && hash != "0" {
-				var block Block
-				blockData := hashToData[hash]
-				block.BlockData = blockData
-				block.BlockSize = int32(len(blockData))
-				err := client.PutBlock(&block, blockStoreAddr, &success)
-				if err != nil {
-					log.Fatal("Error putting block" + err.Error())
-				}
-			} else {
-				break
+// removeStaleDownloads removes the temp files of downloads that never made
+// it to their rename, anywhere under baseDir
+func removeStaleDownloads(baseDir string) error {
+	return filepath.WalkDir(baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				// removed while walking
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() && strings.HasPrefix(d.Name(), DOWNLOAD_FILE_PREFIX) {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
 			}
+		}
+		return nil
+	})
+}
 
+// saveConflictCopy moves a locally edited file that lost a race with a
+// remote update out of the way, to a name like
+// "name (conflict from <host> <timestamp>).ext", and syncs it as a new file.
+// The name is one neither the base directory nor remoteIndex has yet.
+func saveConflictCopy(ctx context.Context, client *RPCClient, parallelism int, localFileMetaData *FileMetaData, hashToData map[string][]byte, remoteIndex map[string]*FileMetaData, report *SyncReport) (*FileMetaData, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown host"
+	}
+	conflictName := conflictFileName(localFileMetaData.Filename, host, time.Now())
+	for i := 2; ; i++ {
+		_, taken := remoteIndex[conflictName]
+		if _, err := os.Stat(client.BaseDir + "/" + conflictName); os.IsNotExist(err) && !taken {
+			break
 		}
+		conflictName = conflictFileName(localFileMetaData.Filename, fmt.Sprintf("%s %d", host, i), time.Now())
+	}
+	err = os.Rename(client.BaseDir+"/"+localFileMetaData.Filename, client.BaseDir+"/"+conflictName)
+	if err != nil {
+		return nil, &SyncError{Op: "save conflict copy of", FileName: localFileMetaData.Filename, Err: err}
+	}
+
+	conflictMetaData := &FileMetaData{Filename: conflictName, Version: 1, BlockHashList: localFileMetaData.BlockHashList}
+	blockMap := make(map[string][]string)
+	err = client.GetBlockStoreMap(ctx, conflictMetaData.BlockHashList, &blockMap)
+	if err != nil {
+		return nil, &SyncError{Op: "get block store map", FileName: conflictName, Err: err}
+	}
+	bytesUploaded, err := addToBlockStore(ctx, client, parallelism, conflictMetaData, hashToData, blockMap)
+	if err != nil {
+		return nil, &SyncError{Op: "upload", FileName: conflictName, Err: err}
 	}
+	err = client.UpdateFile(ctx, conflictMetaData, &conflictMetaData.Version)
+	if err != nil {
+		return nil, &SyncError{Op: "update", FileName: conflictName, Err: err}
+	}
+	report.Conflicts = append(report.Conflicts, SyncConflict{FileName: localFileMetaData.Filename, ConflictCopy: conflictName})
+	report.Uploaded = append(report.Uploaded, conflictName)
+	report.BytesUploaded += bytesUploaded
+	return conflictMetaData, nil
+}
+
+// conflictFileName inserts the conflict marker between a file's name and
+// its extension
+func conflictFileName(fileName string, host string, t time.Time) string {
+	dir, base := "", fileName
+	if i := strings.LastIndex(fileName, "/"); i >= 0 {
+		dir, base = fileName[:i+1], fileName[i+1:]
+	}
+	ext := filepath.Ext(base)
+	if ext == base {
+		// dotfiles like .bashrc have no extension
+		ext = ""
+	}
+	marker := fmt.Sprintf(" (conflict from %s %s)", strings.ReplaceAll(host, ",", " "), t.Format("2006-01-02 150405"))
+	return dir + strings.TrimSuffix(base, ext) + marker + ext
 }
 
-func editFile(filePath string, fileMetaData *FileMetaData, client RPCClient) {
-	//delete the current file
-	err := os.Remove(filePath)
+// editFile makes the file at filePath match fileMetaData, deleting it for a
+// tombstone. The blocks are downloaded before the file is touched and the
+// new contents replace it in a single rename.
+func editFile(ctx context.Context, client *RPCClient, parallelism int, filePath string, fileMetaData *FileMetaData, report *SyncReport) error {
+	if fileMetaData.BlockHashList[0] == TOMBSTONE_HASHVALUE {
+		err := os.Remove(filePath)
+		if err != nil && !os.IsNotExist(err) {
+			return &SyncError{Op: "delete", FileName: fileMetaData.Filename, Err: err}
+		}
+		removeEmptyParents(client.BaseDir, filePath)
+		report.Deleted = append(report.Deleted, fileMetaData.Filename)
+		return nil
+	}
+	hashToData, err := fetchBlocks(ctx, client, parallelism, fileMetaData.BlockHashList)
 	if err != nil {
-		fmt.Println("file doesn't need to be removed")
+		return &SyncError{Op: "download", FileName: fileMetaData.Filename, Err: err}
 	}
-	//reconstitute the file
-	if fileMetaData.BlockHashList[0] == "0" {
-		// fmt.Println("FILE HAS BEEN DELETED")
-		return
+	err = os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return &SyncError{Op: "create parent directories of", FileName: fileMetaData.Filename, Err: err}
 	}
-	file, err := os.Create(filePath)
+	bytesDownloaded, err := materializeFile(filePath, fileMetaData.BlockHashList, hashToData)
 	if err != nil {
-		log.Fatal("Error creating file")
+		return &SyncError{Op: "write", FileName: fileMetaData.Filename, Err: err}
 	}
-	defer file.Close()
-	writeToFile(fileMetaData.BlockHashList, file, client)
-	// for _, hash := range fileMetaData.BlockHashList {
-	// 	var block Block
-	// 	blockStoreAddr := RPCClient.GetResponsibleServer(hash)
-	// 	err := client.GetBlock(hash, blockStoreAddr, &block)
-	// 	if err != nil {
-	// 		log.Fatal("Error getting block" + err.Error())
-	// 	}
-	// 	// add the block to the block list
-	// 	blockData := block.BlockData
-	// 	_, err = file.Write(blockData)
-	// 	if err != nil {
-	// 		log.Fatal("Error writing block to file")
-	// 	}
-	// }
+	report.Downloaded = append(report.Downloaded, fileMetaData.Filename)
+	report.BytesDownloaded += bytesDownloaded
+	return nil
 }
 
 func sameList(list1 []string, list2 []string) bool {
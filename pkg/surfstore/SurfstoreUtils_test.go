@@ -0,0 +1,676 @@
+package surfstore
+
+import (
+	"bytes"
+	context "context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stallingBlockStore never answers block transfers, like a BlockStore that
+// hangs
+type stallingBlockStore struct {
+	*BlockStore
+}
+
+func (bs *stallingBlockStore) MissingBlocks(ctx context.Context, blockHashesIn *BlockHashes) (*BlockHashes, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (bs *stallingBlockStore) GetBlocks(blockHashesIn *BlockHashes, stream BlockStore_GetBlocksServer) error {
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+// startStallingServer serves a MetaStore together with a stalling BlockStore
+func startStallingServer(t *testing.T) (*MetaStore, string) {
+	var metaStore *MetaStore
+	addr := startTestServer(t, func(server *grpc.Server, addr string) {
+		var err error
+		metaStore, err = NewMetaStore([]string{addr}, "", DEFAULT_FILE_HISTORY_SIZE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		RegisterMetaStoreServer(server, metaStore)
+		RegisterBlockStoreServer(server, &stallingBlockStore{NewBlockStore()})
+	})
+	return metaStore, addr
+}
+
+// syncWithTimeout runs a sync that has to give up on the stalled BlockStore
+func syncWithTimeout(t *testing.T, client *RPCClient) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := Sync(ctx, SyncOptions{Client: client})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want a deadline exceeded error", err)
+	}
+	var syncErr *SyncError
+	if !errors.As(err, &syncErr) {
+		t.Fatalf("got %T, want a *SyncError", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("sync took %v to notice the deadline", elapsed)
+	}
+}
+
+func TestSyncCancelledUpload(t *testing.T) {
+	metaStore, addr := startStallingServer(t)
+	baseDir := t.TempDir()
+	if err := os.WriteFile(ConcatPath(baseDir, "new.txt"), []byte("new file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	client := NewSurfstoreRPCClient(addr, baseDir, 4)
+	defer client.Close()
+
+	syncWithTimeout(t, client)
+
+	if _, ok := metaStore.FileMetaMap["new.txt"]; ok {
+		t.Fatal("new.txt was committed without its blocks")
+	}
+	if _, err := os.Stat(ConcatPath(baseDir, DEFAULT_META_FILENAME)); !os.IsNotExist(err) {
+		t.Fatal("index.db was written by a cancelled sync")
+	}
+}
+
+func TestSyncCancelledDownload(t *testing.T) {
+	metaStore, addr := startStallingServer(t)
+	baseDir := t.TempDir()
+	// synced at version 1, the MetaStore has moved on to version 2
+	if err := os.WriteFile(ConcatPath(baseDir, "f.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	synced := &FileMetaData{Filename: "f.txt", Version: 1, BlockHashList: []string{GetBlockHashString([]byte("old"))}}
+	if err := WriteMetaFile(map[string]*FileMetaData{"f.txt": synced}, baseDir); err != nil {
+		t.Fatal(err)
+	}
+	for _, fileMetaData := range []*FileMetaData{synced, {Filename: "f.txt", Version: 2, BlockHashList: []string{GetBlockHashString([]byte("new"))}}} {
+		if _, err := metaStore.UpdateFile(context.Background(), fileMetaData); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := NewSurfstoreRPCClient(addr, baseDir, 4)
+	defer client.Close()
+
+	syncWithTimeout(t, client)
+
+	if data, err := os.ReadFile(ConcatPath(baseDir, "f.txt")); err != nil || string(data) != "old" {
+		t.Fatalf("f.txt is %q (%v) after a cancelled download, want the old contents", data, err)
+	}
+	localIndex, err := LoadMetaFromMetaFile(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if localIndex["f.txt"].Version != 1 {
+		t.Fatalf("index.db has f.txt at version %d, want 1", localIndex["f.txt"].Version)
+	}
+	assertNoDownloadFiles(t, baseDir)
+}
+
+// Only errors caused by cancellation are replaced by ctx.Err() once ctx is
+// done, a sync that failed for another reason keeps its cause
+func TestIsCancellation(t *testing.T) {
+	for _, err := range []error{
+		context.Canceled,
+		fmt.Errorf("uploading: %w", context.DeadlineExceeded),
+		status.Error(codes.Canceled, "context canceled"),
+		status.Error(codes.DeadlineExceeded, "context deadline exceeded"),
+	} {
+		if !isCancellation(err) {
+			t.Fatalf("%v is not taken for a cancellation", err)
+		}
+	}
+	for _, err := range []error{
+		&BlockCorruptionError{Hash: "h"},
+		status.Error(codes.Unavailable, "connection refused"),
+		os.ErrPermission,
+	} {
+		if isCancellation(err) {
+			t.Fatalf("%v is taken for a cancellation", err)
+		}
+	}
+}
+
+func assertNoDownloadFiles(t *testing.T, baseDir string) {
+	t.Helper()
+	err := filepath.WalkDir(baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), DOWNLOAD_FILE_PREFIX) {
+			t.Fatalf("%s was left in the base directory", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startSyncServer serves a MetaStore together with its one BlockStore
+func startSyncServer(t *testing.T) (*MetaStore, string) {
+	t.Helper()
+	var metaStore *MetaStore
+	addr := startTestServer(t, func(server *grpc.Server, addr string) {
+		var err error
+		metaStore, err = NewMetaStore([]string{addr}, "", DEFAULT_FILE_HISTORY_SIZE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		RegisterMetaStoreServer(server, metaStore)
+		RegisterBlockStoreServer(server, NewBlockStore())
+	})
+	return metaStore, addr
+}
+
+// syncDir syncs baseDir through the MetaStore at addr with 4 byte blocks
+func syncDir(t *testing.T, addr string, baseDir string) *SyncReport {
+	t.Helper()
+	client := NewSurfstoreRPCClient(addr, baseDir, 4)
+	defer client.Close()
+	report, err := Sync(context.Background(), SyncOptions{Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+// A download replaces the old file in place, keeping its mode, and the
+// temp files of earlier crashed downloads are cleaned up
+func TestSyncReplacesDownloadedFile(t *testing.T) {
+	metaStore, addr := startSyncServer(t)
+	uploadDir, downloadDir := t.TempDir(), t.TempDir()
+	for _, baseDir := range []string{uploadDir, downloadDir} {
+		if err := os.WriteFile(ConcatPath(baseDir, "f.txt"), []byte("old"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stale := ConcatPath(downloadDir, DOWNLOAD_FILE_PREFIX+"123")
+	if err := os.WriteFile(stale, []byte("half a downl"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(ConcatPath(downloadDir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	staleInSub := ConcatPath(downloadDir, "sub/"+DOWNLOAD_FILE_PREFIX+"456")
+	if err := os.WriteFile(staleInSub, []byte("half a downl"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	syncDir(t, addr, uploadDir)
+	syncDir(t, addr, downloadDir)
+	if err := os.WriteFile(ConcatPath(uploadDir, "f.txt"), []byte("new contents"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	syncDir(t, addr, uploadDir)
+	syncDir(t, addr, downloadDir)
+
+	info, err := os.Stat(ConcatPath(downloadDir, "f.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("f.txt has mode %v after the download, want %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+	if data, err := os.ReadFile(ConcatPath(downloadDir, "f.txt")); err != nil || string(data) != "new contents" {
+		t.Fatalf("f.txt is %q (%v), want the new contents", data, err)
+	}
+	assertNoDownloadFiles(t, downloadDir)
+	if _, ok := metaStore.FileMetaMap[filepath.Base(stale)]; ok {
+		t.Fatal("the stale download was synced")
+	}
+}
+
+// countingBlockStore tracks how many block streams are open across every
+// BlockStore sharing the same counter
+type countingBlockStore struct {
+	*BlockStore
+	mtx         *sync.Mutex
+	inFlight    *int
+	maxInFlight *int
+}
+
+func (bs *countingBlockStore) track() func() {
+	bs.mtx.Lock()
+	*bs.inFlight++
+	if *bs.inFlight > *bs.maxInFlight {
+		*bs.maxInFlight = *bs.inFlight
+	}
+	bs.mtx.Unlock()
+	// long enough for streams that can overlap to do so
+	time.Sleep(20 * time.Millisecond)
+	return func() {
+		bs.mtx.Lock()
+		*bs.inFlight--
+		bs.mtx.Unlock()
+	}
+}
+
+func (bs *countingBlockStore) PutBlocks(stream BlockStore_PutBlocksServer) error {
+	defer bs.track()()
+	return bs.BlockStore.PutBlocks(stream)
+}
+
+func (bs *countingBlockStore) GetBlocks(blockHashesIn *BlockHashes, stream BlockStore_GetBlocksServer) error {
+	defer bs.track()()
+	return bs.BlockStore.GetBlocks(blockHashesIn, stream)
+}
+
+// A file spread over several BlockStores is moved with no more than
+// Parallelism streams at once and comes back byte for byte
+func TestSyncParallelTransfers(t *testing.T) {
+	const parallelism = 2
+	var mtx sync.Mutex
+	var inFlight, maxInFlight int
+	blockStoreAddrs := []string{}
+	for i := 0; i < 3; i++ {
+		blockStoreAddrs = append(blockStoreAddrs, startTestServer(t, func(server *grpc.Server, _ string) {
+			RegisterBlockStoreServer(server, &countingBlockStore{NewBlockStore(), &mtx, &inFlight, &maxInFlight})
+		}))
+	}
+	metaStore, err := NewMetaStore(blockStoreAddrs, "", DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metaAddr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterMetaStoreServer(server, metaStore)
+	})
+
+	data := make([]byte, 4*BLOCK_TRANSFER_BATCH_SIZE*6)
+	rand.New(rand.NewSource(1)).Read(data)
+	uploadDir, downloadDir := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(ConcatPath(uploadDir, "big.bin"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, baseDir := range []string{uploadDir, downloadDir} {
+		client := NewSurfstoreRPCClient(metaAddr, baseDir, 4)
+		_, err := Sync(context.Background(), SyncOptions{Client: client, Parallelism: parallelism})
+		client.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	downloaded, err := os.ReadFile(ConcatPath(downloadDir, "big.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Fatal("big.bin was not reassembled in order")
+	}
+	if maxInFlight > parallelism {
+		t.Fatalf("%d block streams were open at once, want at most %d", maxInFlight, parallelism)
+	}
+	if maxInFlight < parallelism {
+		t.Fatalf("at most %d block stream was open at once, transfers did not run in parallel", maxInFlight)
+	}
+}
+
+func writeTestFile(t *testing.T, baseDir string, fileName string, data string) {
+	t.Helper()
+	filePath := filepath.Join(baseDir, filepath.FromSlash(fileName))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertTestFile(t *testing.T, baseDir string, fileName string, want string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(baseDir, filepath.FromSlash(fileName)))
+	if err != nil || string(data) != want {
+		t.Fatalf("%s is %q (%v), want %q", fileName, data, err, want)
+	}
+}
+
+// Files in subdirectories are synced under their slash separated path,
+// and a remote delete removes the directories it leaves empty
+func TestSyncSubdirectories(t *testing.T) {
+	metaStore, addr := startSyncServer(t)
+	uploadDir, downloadDir := t.TempDir(), t.TempDir()
+	writeTestFile(t, uploadDir, "a/b/deep.txt", "deep file")
+	writeTestFile(t, uploadDir, "a/shallow.txt", "shallow file")
+	writeTestFile(t, uploadDir, "top.txt", "top file")
+	syncDir(t, addr, uploadDir)
+	for _, fileName := range []string{"a/b/deep.txt", "a/shallow.txt", "top.txt"} {
+		if _, ok := metaStore.FileMetaMap[fileName]; !ok {
+			t.Fatalf("%s was not committed", fileName)
+		}
+	}
+
+	syncDir(t, addr, downloadDir)
+	assertTestFile(t, downloadDir, "a/b/deep.txt", "deep file")
+	assertTestFile(t, downloadDir, "a/shallow.txt", "shallow file")
+	assertTestFile(t, downloadDir, "top.txt", "top file")
+	assertNoDownloadFiles(t, downloadDir)
+
+	if err := os.RemoveAll(filepath.Join(uploadDir, "a", "b")); err != nil {
+		t.Fatal(err)
+	}
+	syncDir(t, addr, uploadDir)
+	report := syncDir(t, addr, downloadDir)
+	if len(report.Deleted) != 1 || report.Deleted[0] != "a/b/deep.txt" {
+		t.Fatalf("deleted %v, want a/b/deep.txt", report.Deleted)
+	}
+	if _, err := os.Stat(filepath.Join(downloadDir, "a", "b")); !os.IsNotExist(err) {
+		t.Fatalf("a/b was left behind empty (%v)", err)
+	}
+	assertTestFile(t, downloadDir, "a/shallow.txt", "shallow file")
+
+	if err := os.Remove(filepath.Join(uploadDir, "a", "shallow.txt")); err != nil {
+		t.Fatal(err)
+	}
+	syncDir(t, addr, uploadDir)
+	syncDir(t, addr, downloadDir)
+	if _, err := os.Stat(filepath.Join(downloadDir, "a")); !os.IsNotExist(err) {
+		t.Fatalf("a was left behind empty (%v)", err)
+	}
+	assertTestFile(t, downloadDir, "top.txt", "top file")
+}
+
+// A local edit that loses to a remote one is kept in exactly one conflict
+// copy, named so it clashes with neither local files nor remote ones
+func TestSyncConflictCopy(t *testing.T) {
+	metaStore, addr := startSyncServer(t)
+	winnerDir, loserDir := t.TempDir(), t.TempDir()
+	writeTestFile(t, winnerDir, "f.txt", "original")
+	syncDir(t, addr, winnerDir)
+	syncDir(t, addr, loserDir)
+
+	writeTestFile(t, winnerDir, "f.txt", "winning edit")
+	writeTestFile(t, loserDir, "f.txt", "losing edit")
+	syncDir(t, addr, winnerDir)
+
+	// another client already committed, and deleted, the conflict copies
+	// this sync would name first
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown host"
+	}
+	now := time.Now()
+	for _, at := range []time.Time{now, now.Add(time.Second)} {
+		taken := &FileMetaData{Filename: conflictFileName("f.txt", host, at), Version: 1, BlockHashList: []string{TOMBSTONE_HASHVALUE}}
+		if _, err := metaStore.UpdateFile(context.Background(), taken); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report := syncDir(t, addr, loserDir)
+
+	if len(report.Conflicts) != 1 {
+		t.Fatalf("got %d conflicts, want 1", len(report.Conflicts))
+	}
+	conflictCopy := report.Conflicts[0].ConflictCopy
+	if !strings.Contains(conflictCopy, host+" 2") {
+		t.Fatalf("conflict copy %q reuses a name the MetaStore has", conflictCopy)
+	}
+	assertTestFile(t, loserDir, conflictCopy, "losing edit")
+	assertTestFile(t, loserDir, "f.txt", "winning edit")
+	fileNames, err := listLocalFiles(loserDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fileNames) != 2 {
+		t.Fatalf("got %v, want f.txt and one conflict copy", fileNames)
+	}
+
+	syncDir(t, addr, winnerDir)
+	assertTestFile(t, winnerDir, conflictCopy, "losing edit")
+	assertTestFile(t, winnerDir, "f.txt", "winning edit")
+}
+
+// changesRecorder records whether each GetChangesSince answer was the full
+// remote index
+type changesRecorder struct {
+	*MetaStore
+	mtx  sync.Mutex
+	full []bool
+}
+
+func (m *changesRecorder) GetChangesSince(ctx context.Context, cursor *ChangeCursor) (*FileInfoChanges, error) {
+	changes, err := m.MetaStore.GetChangesSince(ctx, cursor)
+	if err == nil {
+		m.mtx.Lock()
+		m.full = append(m.full, changes.Full)
+		m.mtx.Unlock()
+	}
+	return changes, err
+}
+
+func (m *changesRecorder) lastFull(t *testing.T) bool {
+	t.Helper()
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if len(m.full) == 0 {
+		t.Fatal("GetChangesSince was never called")
+	}
+	return m.full[len(m.full)-1]
+}
+
+func startChangesRecorder(t *testing.T) (*changesRecorder, string) {
+	t.Helper()
+	var recorder *changesRecorder
+	addr := startTestServer(t, func(server *grpc.Server, addr string) {
+		metaStore, err := NewMetaStore([]string{addr}, "", DEFAULT_FILE_HISTORY_SIZE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorder = &changesRecorder{MetaStore: metaStore}
+		RegisterMetaStoreServer(server, recorder)
+		RegisterBlockStoreServer(server, NewBlockStore())
+	})
+	return recorder, addr
+}
+
+// A sync after the first only fetches the changes since its cursor, and
+// still sees the files that did not change
+func TestSyncResumesFromCursor(t *testing.T) {
+	recorder, addr := startChangesRecorder(t)
+	uploadDir, downloadDir := t.TempDir(), t.TempDir()
+	writeTestFile(t, uploadDir, "a.txt", "first a")
+	writeTestFile(t, uploadDir, "b.txt", "first b")
+	syncDir(t, addr, uploadDir)
+	syncDir(t, addr, downloadDir)
+	if !recorder.lastFull(t) {
+		t.Fatal("a first sync was sent only changes")
+	}
+
+	writeTestFile(t, uploadDir, "a.txt", "second a")
+	syncDir(t, addr, uploadDir)
+	report := syncDir(t, addr, downloadDir)
+	if recorder.lastFull(t) {
+		t.Fatal("a sync with a cursor was sent the full remote index")
+	}
+	if len(report.Downloaded) != 1 || report.Downloaded[0] != "a.txt" {
+		t.Fatalf("downloaded %v, want a.txt", report.Downloaded)
+	}
+	assertTestFile(t, downloadDir, "a.txt", "second a")
+	localIndex, err := LoadMetaFromMetaFile(downloadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if localIndex["b.txt"] == nil || localIndex["a.txt"].Version != 2 {
+		t.Fatalf("index.db is %v after an incremental sync", localIndex)
+	}
+}
+
+// A cursor the MetaStore never handed out, one from before it lost its
+// state, gets the full remote index
+func TestSyncFallsBackFromUnknownCursor(t *testing.T) {
+	recorder, addr := startChangesRecorder(t)
+	uploadDir, downloadDir := t.TempDir(), t.TempDir()
+	writeTestFile(t, uploadDir, "a.txt", "first a")
+	syncDir(t, addr, uploadDir)
+	syncDir(t, addr, downloadDir)
+	if err := writeCursorFile(downloadDir, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, uploadDir, "b.txt", "first b")
+	syncDir(t, addr, uploadDir)
+	syncDir(t, addr, downloadDir)
+	if !recorder.lastFull(t) {
+		t.Fatal("an unknown cursor was sent only changes")
+	}
+	assertTestFile(t, downloadDir, "a.txt", "first a")
+	assertTestFile(t, downloadDir, "b.txt", "first b")
+	if cursor, err := readCursorFile(downloadDir); err != nil || cursor != 2 {
+		t.Fatalf("cursor is %d (%v) after the fallback, want 2", cursor, err)
+	}
+}
+
+// A remote file a sync left out of index.db is fetched again by the next
+// sync, even though it did not change remotely
+func TestSyncRefetchesSkippedFile(t *testing.T) {
+	recorder, addr := startChangesRecorder(t)
+	uploadDir, downloadDir := t.TempDir(), t.TempDir()
+	writeTestFile(t, uploadDir, "a.txt", "first a")
+	writeTestFile(t, uploadDir, "b.txt", "first b")
+	syncDir(t, addr, uploadDir)
+	syncDir(t, addr, downloadDir)
+
+	// index.db claims a version of b.txt the MetaStore never had, so when
+	// b.txt changes remotely the sync skips it as having an invalid version
+	localIndex, err := LoadMetaFromMetaFile(downloadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localIndex["b.txt"].Version = 5
+	if err := WriteMetaFile(localIndex, downloadDir); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, uploadDir, "b.txt", "second b")
+	syncDir(t, addr, uploadDir)
+	syncDir(t, addr, downloadDir)
+	localIndex, err = LoadMetaFromMetaFile(downloadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := localIndex["b.txt"]; ok {
+		t.Fatal("b.txt with an invalid version was not skipped")
+	}
+
+	syncDir(t, addr, downloadDir)
+	if !recorder.lastFull(t) {
+		t.Fatal("the sync after skipping b.txt was sent only changes")
+	}
+	localIndex, err = LoadMetaFromMetaFile(downloadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if localIndex["b.txt"] == nil || localIndex["b.txt"].Version != 2 {
+		t.Fatalf("b.txt is %v in index.db, want version 2", localIndex["b.txt"])
+	}
+	assertTestFile(t, downloadDir, "b.txt", "second b")
+}
+
+func sortedNames(names []string) []string {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func assertNames(t *testing.T, what string, got []string, want ...string) {
+	t.Helper()
+	got = sortedNames(got)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("%s %v, want %v", what, got, want)
+	}
+}
+
+// The report lists the files a sync uploaded, downloaded and deleted, and
+// counts the block bytes it moved
+func TestSyncReport(t *testing.T) {
+	_, addr := startSyncServer(t)
+	uploadDir, downloadDir := t.TempDir(), t.TempDir()
+	writeTestFile(t, uploadDir, "a.txt", "aaaabbbb")
+	writeTestFile(t, uploadDir, "b.txt", "cccc")
+	report := syncDir(t, addr, uploadDir)
+	assertNames(t, "uploaded", report.Uploaded, "a.txt", "b.txt")
+	if report.BytesUploaded != 12 || len(report.Downloaded) != 0 || len(report.Deleted) != 0 {
+		t.Fatalf("first upload reported %+v, want 12 bytes uploaded and nothing else", report)
+	}
+	report = syncDir(t, addr, downloadDir)
+	assertNames(t, "downloaded", report.Downloaded, "a.txt", "b.txt")
+	if report.BytesDownloaded != 12 || len(report.Uploaded) != 0 {
+		t.Fatalf("first download reported %+v, want 12 bytes downloaded and nothing else", report)
+	}
+
+	// only the new block of a.txt is sent
+	writeTestFile(t, uploadDir, "a.txt", "aaaabbbbdddd")
+	if err := os.Remove(ConcatPath(uploadDir, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	report = syncDir(t, addr, uploadDir)
+	assertNames(t, "uploaded", report.Uploaded, "a.txt")
+	assertNames(t, "deleted", report.Deleted, "b.txt")
+	if report.BytesUploaded != 4 {
+		t.Fatalf("uploaded %d bytes, want the 4 bytes of the new block", report.BytesUploaded)
+	}
+	report = syncDir(t, addr, downloadDir)
+	assertNames(t, "downloaded", report.Downloaded, "a.txt")
+	assertNames(t, "deleted", report.Deleted, "b.txt")
+	if report.BytesDownloaded != 12 || len(report.Uploaded) != 0 {
+		t.Fatalf("second download reported %+v, want the 12 bytes of a.txt downloaded", report)
+	}
+}
+
+// A step that fails is returned as a *SyncError naming the step and the
+// file, wrapping the cause
+func TestSyncErrorNamesStep(t *testing.T) {
+	addr := startTestServer(t, func(server *grpc.Server, addr string) {
+		metaStore, err := NewMetaStore([]string{addr}, "", DEFAULT_FILE_HISTORY_SIZE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		RegisterMetaStoreServer(server, metaStore)
+		RegisterBlockStoreServer(server, &corruptingBlockStore{NewBlockStore()})
+	})
+	uploadDir, downloadDir := t.TempDir(), t.TempDir()
+	writeTestFile(t, uploadDir, "f.txt", "some data")
+	syncDir(t, addr, uploadDir)
+
+	client := NewSurfstoreRPCClient(addr, downloadDir, 4)
+	defer client.Close()
+	_, err := Sync(context.Background(), SyncOptions{Client: client})
+	var syncErr *SyncError
+	if !errors.As(err, &syncErr) {
+		t.Fatalf("got %v, want a *SyncError", err)
+	}
+	if syncErr.Op != "download" || syncErr.FileName != "f.txt" {
+		t.Fatalf("failed at %q %q, want the download of f.txt", syncErr.Op, syncErr.FileName)
+	}
+	var corruption *BlockCorruptionError
+	if !errors.As(err, &corruption) {
+		t.Fatalf("got %v, want it to wrap a BlockCorruptionError", err)
+	}
+}
+
+// The deprecated ClientSync still syncs
+func TestClientSync(t *testing.T) {
+	metaStore, addr := startSyncServer(t)
+	baseDir := t.TempDir()
+	writeTestFile(t, baseDir, "f.txt", "some data")
+	client := NewSurfstoreRPCClient(addr, baseDir, 4)
+	defer client.Close()
+	ClientSync(client)
+	if got := committedVersion(t, metaStore, "f.txt"); got != 1 {
+		t.Fatalf("f.txt at version %d after ClientSync, want 1", got)
+	}
+}
@@ -0,0 +1,64 @@
+package surfstore
+
+import (
+	context "context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ClientRestore brings fileName back to a version the MetaStore retained.
+// The old block list is committed as the file's newest version, so every
+// client picks the restore up, and then the base directory is synced. Local
+// edits to the file that were never synced are kept as a conflict copy.
+func ClientRestore(ctx context.Context, opts SyncOptions, fileName string, version int32) (*SyncReport, error) {
+	client := opts.Client
+	var oldFileMetaData FileMetaData
+	if err := client.GetFileVersion(ctx, fileName, version, &oldFileMetaData); err != nil {
+		return nil, err
+	}
+	var versions []*FileMetaData
+	if err := client.ListFileVersions(ctx, fileName, &versions); err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		// the file went away since GetFileVersion
+		return nil, status.Errorf(codes.NotFound, "File %s not found", fileName)
+	}
+	current := versions[len(versions)-1]
+
+	if current.Version != version {
+		restored := &FileMetaData{Filename: fileName, Version: current.Version + 1, BlockHashList: oldFileMetaData.BlockHashList}
+		var latestVersion int32
+		if err := client.UpdateFile(ctx, restored, &latestVersion); err != nil {
+			return nil, err
+		}
+	}
+	return Sync(ctx, opts)
+}
+
+// describeVersion summarises a file version for listing
+func describeVersion(fileMetaData *FileMetaData) string {
+	hashList := fileMetaData.BlockHashList
+	if len(hashList) == 1 && hashList[0] == TOMBSTONE_HASHVALUE {
+		return "deleted"
+	}
+	if len(hashList) == 1 && hashList[0] == EMPTYFILE_HASHVALUE {
+		return "empty"
+	}
+	return fmt.Sprintf("%d blocks", len(hashList))
+}
+
+// PrintFileVersions lists the versions of fileName the MetaStore retained,
+// oldest first
+func PrintFileVersions(ctx context.Context, client *RPCClient, fileName string) error {
+	var versions []*FileMetaData
+	if err := client.ListFileVersions(ctx, fileName, &versions); err != nil {
+		return err
+	}
+	for _, fileMetaData := range versions {
+		fmt.Printf("%s version %d: %s\n", fileName, fileMetaData.Version, describeVersion(fileMetaData))
+	}
+	return nil
+}
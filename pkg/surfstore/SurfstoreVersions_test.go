@@ -0,0 +1,69 @@
+package surfstore
+
+import (
+	context "context"
+	"testing"
+
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Restoring an old version commits it as the newest one, and the base
+// directory gets its contents back
+func TestClientRestore(t *testing.T) {
+	metaStore, addr := startSyncServer(t)
+	baseDir, otherDir := t.TempDir(), t.TempDir()
+	for _, data := range []string{"version one", "version two", "version three"} {
+		writeTestFile(t, baseDir, "f.txt", data)
+		syncDir(t, addr, baseDir)
+	}
+
+	client := NewSurfstoreRPCClient(addr, baseDir, 4)
+	defer client.Close()
+	if _, err := ClientRestore(context.Background(), SyncOptions{Client: client}, "f.txt", 1); err != nil {
+		t.Fatal(err)
+	}
+	assertTestFile(t, baseDir, "f.txt", "version one")
+	if got := committedVersion(t, metaStore, "f.txt"); got != 4 {
+		t.Fatalf("restored f.txt is at version %d, want 4", got)
+	}
+	localIndex, err := LoadMetaFromMetaFile(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if localIndex["f.txt"].Version != 4 {
+		t.Fatalf("index.db has f.txt at version %d, want 4", localIndex["f.txt"].Version)
+	}
+
+	syncDir(t, addr, otherDir)
+	assertTestFile(t, otherDir, "f.txt", "version one")
+}
+
+// noVersionsMetaStore has every version of a file but lists none of them,
+// like a file removed between the two calls
+type noVersionsMetaStore struct {
+	*MetaStore
+}
+
+func (m *noVersionsMetaStore) ListFileVersions(ctx context.Context, fileName *FileName) (*FileVersions, error) {
+	return &FileVersions{}, nil
+}
+
+func TestClientRestoreNoVersions(t *testing.T) {
+	metaStore, err := NewMetaStore(nil, "", DEFAULT_FILE_HISTORY_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitVersion(t, metaStore, "f.txt", 1)
+	addr := startTestServer(t, func(server *grpc.Server, _ string) {
+		RegisterMetaStoreServer(server, &noVersionsMetaStore{metaStore})
+	})
+	client := NewSurfstoreRPCClient(addr, t.TempDir(), 4)
+	defer client.Close()
+
+	_, err = ClientRestore(context.Background(), SyncOptions{Client: client}, "f.txt", 1)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("got %v, want NotFound", err)
+	}
+}
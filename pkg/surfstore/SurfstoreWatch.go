@@ -0,0 +1,250 @@
+package surfstore
+
+import (
+	context "context"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type WatchOptions struct {
+	// How often the base directory is scanned for local changes
+	PollInterval time.Duration
+	// How long the base directory has to stay unchanged before a local
+	// change is synced, so a burst of writes results in a single sync
+	Debounce time.Duration
+	// How often to sync when nothing changes locally, in case a remote
+	// change notification was missed
+	PullInterval time.Duration
+}
+
+// localFileState is what a scan remembers about a file to notice changes
+type localFileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// remoteChangeSet collects the changes the MetaStore pushed since ClientWatch
+// last looked at them
+type remoteChangeSet struct {
+	mtx      sync.Mutex
+	versions map[string]int32
+	// changes were missed, everything has to be synced
+	all bool
+}
+
+func (rc *remoteChangeSet) add(change *FileChange) {
+	rc.mtx.Lock()
+	defer rc.mtx.Unlock()
+	if rc.versions == nil {
+		rc.versions = make(map[string]int32)
+	}
+	if change.Version > rc.versions[change.Filename] {
+		rc.versions[change.Filename] = change.Version
+	}
+}
+
+func (rc *remoteChangeSet) addAll() {
+	rc.mtx.Lock()
+	defer rc.mtx.Unlock()
+	rc.all = true
+}
+
+// newerThan empties the set and reports whether it held a change the
+// synced versions do not have yet
+func (rc *remoteChangeSet) newerThan(synced map[string]int32) bool {
+	rc.mtx.Lock()
+	defer rc.mtx.Unlock()
+	newer := rc.all
+	for fileName, version := range rc.versions {
+		if version > synced[fileName] {
+			newer = true
+		}
+	}
+	rc.versions = nil
+	rc.all = false
+	return newer
+}
+
+// syncedVersions returns the version of every file in index.db
+func syncedVersions(baseDir string) map[string]int32 {
+	versions := make(map[string]int32)
+	localIndex, err := LoadMetaFromMetaFile(baseDir)
+	if err != nil {
+		log.Println("Error loading index.db:", err)
+		return versions
+	}
+	for fileName, fileMetaData := range localIndex {
+		versions[fileName] = fileMetaData.Version
+	}
+	return versions
+}
+
+// ClientWatch syncs the base directory and then keeps it in sync until ctx
+// is cancelled. Local changes are found by polling, remote changes are
+// pushed by the MetaStore's WatchChanges stream. Changes the MetaStore
+// pushes for versions index.db already has, such as the ones a sync just
+// uploaded, do not start another sync. A sync that is running when ctx is
+// cancelled stops early, see Sync for what that leaves behind.
+func ClientWatch(ctx context.Context, opts SyncOptions, options WatchOptions) {
+	client := opts.Client
+	if options.PollInterval <= 0 {
+		options.PollInterval = DEFAULT_WATCH_POLL_INTERVAL
+	}
+	if options.PullInterval <= 0 {
+		options.PullInterval = DEFAULT_WATCH_PULL_INTERVAL
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	remoteChanges := make(chan struct{}, 1)
+	changeSet := &remoteChangeSet{}
+	go watchRemoteChanges(ctx, client, options.PullInterval, changeSet, remoteChanges)
+
+	syncAndLog(ctx, opts)
+	lastSync := time.Now()
+	synced := syncedVersions(client.BaseDir)
+	lastScan, err := scanLocalState(client.BaseDir)
+	if err != nil {
+		log.Println("Error scanning base directory:", err)
+	}
+	dirty := false
+	remoteDirty := false
+	var lastChange time.Time
+
+	ticker := time.NewTicker(options.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-remoteChanges:
+			// picked up by the next scan, after any local burst settles
+			if changeSet.newerThan(synced) {
+				remoteDirty = true
+			}
+		case now := <-ticker.C:
+			scan, err := scanLocalState(client.BaseDir)
+			if err != nil {
+				log.Println("Error scanning base directory:", err)
+				continue
+			}
+			if !sameLocalState(scan, lastScan) {
+				dirty = true
+				lastChange = now
+				lastScan = scan
+			}
+			if dirty && now.Sub(lastChange) < options.Debounce {
+				continue
+			}
+			if !dirty && !remoteDirty && now.Sub(lastSync) < options.PullInterval {
+				continue
+			}
+
+			// don't start another sync once asked to stop
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("Syncing", client.BaseDir)
+			syncAndLog(ctx, opts)
+			lastSync = time.Now()
+			synced = syncedVersions(client.BaseDir)
+			dirty = false
+			remoteDirty = false
+			// downloads changed the directory, they are not local changes
+			if scan, err := scanLocalState(client.BaseDir); err == nil {
+				lastScan = scan
+			}
+		}
+	}
+}
+
+// syncAndLog runs a sync for ClientWatch, a sync that fails is logged and
+// retried by the next one
+func syncAndLog(ctx context.Context, opts SyncOptions) {
+	report, err := Sync(ctx, opts)
+	if err != nil {
+		log.Println("Error syncing:", err)
+		return
+	}
+	for _, conflict := range report.Conflicts {
+		log.Printf("Conflict: %s was changed remotely, local changes saved as %s\n", conflict.FileName, conflict.ConflictCopy)
+	}
+	log.Printf("Synced %s: %d uploaded, %d downloaded, %d deleted\n", opts.Client.BaseDir, len(report.Uploaded), len(report.Downloaded), len(report.Deleted))
+}
+
+// watchRemoteChanges adds every change the MetaStore commits to changeSet
+// and signals remoteChanges, reconnecting every retryInterval if the stream
+// fails
+func watchRemoteChanges(ctx context.Context, client *RPCClient, retryInterval time.Duration, changeSet *remoteChangeSet, remoteChanges chan<- struct{}) {
+	notify := func() {
+		select {
+		case remoteChanges <- struct{}{}:
+		default:
+		}
+	}
+	afterSequence := int64(-1)
+	for {
+		err := client.WatchChanges(ctx, afterSequence, func(change *FileChange) error {
+			afterSequence = change.Sequence
+			changeSet.add(change)
+			notify()
+			return nil
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		switch status.Code(err) {
+		case codes.Unimplemented:
+			log.Println("MetaStore does not support WatchChanges, pulling every", retryInterval)
+			return
+		case codes.OutOfRange:
+			// missed changes, sync everything and start over
+			afterSequence = -1
+			changeSet.addAll()
+			notify()
+		}
+		log.Println("Error watching remote changes:", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// scanLocalState records the size and modification time of every file the
+// client would sync
+func scanLocalState(baseDir string) (map[string]localFileState, error) {
+	fileNames, err := listLocalFiles(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	state := make(map[string]localFileState, len(fileNames))
+	for _, fileName := range fileNames {
+		info, err := os.Stat(ConcatPath(baseDir, fileName))
+		if err != nil {
+			// removed since it was listed, the next scan notices
+			continue
+		}
+		state[fileName] = localFileState{size: info.Size(), modTime: info.ModTime()}
+	}
+	return state, nil
+}
+
+func sameLocalState(a map[string]localFileState, b map[string]localFileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for fileName, stateA := range a {
+		stateB, ok := b[fileName]
+		if !ok || stateA.size != stateB.size || !stateA.modTime.Equal(stateB.modTime) {
+			return false
+		}
+	}
+	return true
+}
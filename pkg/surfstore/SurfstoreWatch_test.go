@@ -0,0 +1,126 @@
+package surfstore
+
+import (
+	context "context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	grpc "google.golang.org/grpc"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// committedVersion returns the version of fileName the MetaStore has, 0 if
+// it has none
+func committedVersion(t *testing.T, metaStore *MetaStore, fileName string) int32 {
+	t.Helper()
+	fileInfoMap, err := metaStore.GetFileInfoMap(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileMetaData, ok := fileInfoMap.FileInfoMap[fileName]; ok {
+		return fileMetaData.Version
+	}
+	return 0
+}
+
+// A burst of writes is synced once, after the directory has been quiet for
+// the debounce delay
+func TestClientWatchDebounce(t *testing.T) {
+	const debounce = 300 * time.Millisecond
+	metaStore, addr := startSyncServer(t)
+	baseDir := t.TempDir()
+	writeTestFile(t, baseDir, "f.txt", "first")
+	client := NewSurfstoreRPCClient(addr, baseDir, 4)
+	t.Cleanup(func() { client.Close() })
+
+	startWatch(t, client, debounce)
+	// give the watcher time for its initial sync and scan
+	time.Sleep(debounce)
+
+	// each write changes the size, so every scan sees it
+	for i := 1; i <= 8; i++ {
+		writeTestFile(t, baseDir, "f.txt", "write"+strings.Repeat("!", i))
+		time.Sleep(debounce / 10)
+	}
+	if got := committedVersion(t, metaStore, "f.txt"); got != 1 {
+		t.Fatalf("synced in the middle of a burst, f.txt is at version %d", got)
+	}
+	waitFor(t, "the burst is synced", func() bool {
+		return committedVersion(t, metaStore, "f.txt") == 2
+	})
+	time.Sleep(2 * debounce)
+	if got := committedVersion(t, metaStore, "f.txt"); got != 2 {
+		t.Fatalf("the burst was synced more than once, f.txt is at version %d", got)
+	}
+	otherDir := t.TempDir()
+	syncDir(t, addr, otherDir)
+	assertTestFile(t, otherDir, "f.txt", "write"+strings.Repeat("!", 8))
+}
+
+// syncCountingMetaStore counts syncs, each of them asks for the changes
+// since its cursor once
+type syncCountingMetaStore struct {
+	*MetaStore
+	syncs atomic.Int32
+}
+
+func (m *syncCountingMetaStore) GetChangesSince(ctx context.Context, cursor *ChangeCursor) (*FileInfoChanges, error) {
+	m.syncs.Add(1)
+	return m.MetaStore.GetChangesSince(ctx, cursor)
+}
+
+// startWatch runs ClientWatch over baseDir until the test ends
+func startWatch(t *testing.T, client *RPCClient, debounce time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ClientWatch(ctx, SyncOptions{Client: client}, WatchOptions{
+			PollInterval: 10 * time.Millisecond,
+			Debounce:     debounce,
+			PullInterval: time.Hour,
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// One local edit is synced once, the MetaStore pushing the upload back to
+// the watcher does not start another sync
+func TestClientWatchNoEchoSync(t *testing.T) {
+	const debounce = 100 * time.Millisecond
+	metaStore := &syncCountingMetaStore{}
+	addr := startTestServer(t, func(server *grpc.Server, addr string) {
+		inner, err := NewMetaStore([]string{addr}, "", DEFAULT_FILE_HISTORY_SIZE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		metaStore.MetaStore = inner
+		RegisterMetaStoreServer(server, metaStore)
+		RegisterBlockStoreServer(server, NewBlockStore())
+	})
+	baseDir := t.TempDir()
+	writeTestFile(t, baseDir, "f.txt", "first")
+	client := NewSurfstoreRPCClient(addr, baseDir, 4)
+	t.Cleanup(func() { client.Close() })
+	startWatch(t, client, debounce)
+	waitFor(t, "the initial sync", func() bool {
+		return committedVersion(t, metaStore.MetaStore, "f.txt") == 1
+	})
+	// an echo of the initial upload would show up here
+	time.Sleep(3 * debounce)
+	before := metaStore.syncs.Load()
+
+	writeTestFile(t, baseDir, "f.txt", "edited")
+	waitFor(t, "the edit is synced", func() bool {
+		return committedVersion(t, metaStore.MetaStore, "f.txt") == 2
+	})
+	time.Sleep(3 * debounce)
+	if syncs := metaStore.syncs.Load() - before; syncs != 1 {
+		t.Fatalf("one edit started %d syncs", syncs)
+	}
+}